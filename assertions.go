@@ -0,0 +1,79 @@
+package scparser
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+// processAssertions adds the interface assertions of the named type found in its package, e.g. `var _ io.Writer = (*T)(nil)`
+func (p *parser) processAssertions(obj *types.TypeName) {
+	if p.seenAssertions[obj] || obj.Pkg() == nil {
+		return
+	}
+	p.seenAssertions[obj] = true
+
+	pkg, ok := p.pkgByPath[obj.Pkg().Path()]
+	if !ok {
+		return
+	}
+
+	for _, file := range pkg.Syntax {
+		for _, decl := range file.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.VAR {
+				continue
+			}
+
+			for _, spec := range gd.Specs {
+				vs, ok := spec.(*ast.ValueSpec)
+				if !ok || !isAssertionOf(pkg.TypesInfo, vs, obj) {
+					continue
+				}
+
+				name := obj.Pkg().Path() + `.` + obj.Name() + ` assertion`
+				src := p.render(name, func() (string, error) {
+					return p.specSource(pkg.Fset, gd, vs)
+				})
+
+				pos := pkg.Fset.Position(vs.Pos())
+				p.addFunction(pkg, function{name: name, start: pos, end: pkg.Fset.Position(vs.End()), src: src, recv: obj.Name(), node: vs})
+			}
+		}
+	}
+}
+
+// isAssertionOf reports whether the value spec is a blank interface assertion of the named type
+func isAssertionOf(info *types.Info, vs *ast.ValueSpec, obj *types.TypeName) bool {
+	if vs.Type == nil || len(vs.Values) == 0 || !types.IsInterface(info.TypeOf(vs.Type)) {
+		return false
+	}
+
+	for _, name := range vs.Names {
+		if name.Name != `_` {
+			return false
+		}
+	}
+
+	for _, value := range vs.Values {
+		if named := namedType(info.TypeOf(value)); named != nil && named.Obj() == obj {
+			return true
+		}
+	}
+
+	return false
+}
+
+// namedType returns the named type of t, or of the type t points to
+func namedType(t types.Type) *types.Named {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+
+	named, _ := t.(*types.Named)
+	if named != nil {
+		named = named.Origin()
+	}
+
+	return named
+}
@@ -0,0 +1,193 @@
+package scparser
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// ParseChanged retrieves the source code of the functions reached from the specified function whose
+// source code differs between two git revisions of the repository containing the package directory.
+// Functions that are only reached at newRev, or of which the source code changed, are included as they
+// are at newRev. Functions that are only reached at oldRev are listed as removed at the end of the output.
+// The ExcludeRoot option is ignored, the unchanged functions are already left out. A *ParseError is returned
+// if one of the revisions can not be extracted or loaded, or the function is not found in it.
+func ParseChanged(funcPkgPath, funcName, oldRev, newRev string, opts Options) (string, error) {
+	oldP, cleanupOld, err := parseRevision(funcPkgPath, funcName, oldRev, opts)
+	if err != nil {
+		return "", &ParseError{PkgPath: funcPkgPath, FuncName: funcName, Err: err}
+	}
+	defer cleanupOld()
+
+	newP, cleanupNew, err := parseRevision(funcPkgPath, funcName, newRev, opts)
+	if err != nil {
+		return "", &ParseError{PkgPath: funcPkgPath, FuncName: funcName, Err: err}
+	}
+	defer cleanupNew()
+
+	oldSrc := functionSources(oldP)
+	newSrc := functionSources(newP)
+
+	// Keep only the functions that were added or changed at the new revision. If the root package is left out,
+	// the first changed package keeps its package header, so its functions are not taken for the root package's.
+	var pkgOrder []*packages.Package
+	for _, pkg := range newP.pkgOrder {
+		var changed []function
+		for _, fn := range newP.functions[pkg] {
+			if src, ok := oldSrc[fn.name]; !ok || src != newSrc[fn.name] {
+				changed = append(changed, fn)
+			}
+		}
+
+		if len(changed) > 0 {
+			pkgOrder = append(pkgOrder, pkg)
+		}
+		newP.functions[pkg] = changed
+	}
+	newP.rootOmitted = len(pkgOrder) > 0 && len(newP.pkgOrder) > 0 && pkgOrder[0] != newP.pkgOrder[0]
+	newP.pkgOrder = pkgOrder
+
+	result := newP.toString(false, opts.CodeOnly)
+
+	// List the functions that are no longer reached at the new revision
+	for _, pkg := range oldP.pkgOrder {
+		for _, fn := range oldP.functions[pkg] {
+			if _, ok := newSrc[fn.name]; !ok {
+				if result != "" {
+					result += "\n"
+				}
+				result += formatNote("Removed: "+fn.name, opts.CodeOnly)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// functionSources returns the combined source code of the processed functions by qualified name
+func functionSources(p *parser) map[string]string {
+	sources := make(map[string]string)
+	for _, pkg := range p.pkgOrder {
+		for _, fn := range p.functions[pkg] {
+			sources[fn.name] += fn.src
+		}
+	}
+
+	return sources
+}
+
+// parseRevision processes the specified function in a copy of the package directory at the given git revision.
+// The returned cleanup function removes the copy.
+func parseRevision(funcPkgPath, funcName, rev string, opts Options) (*parser, func(), error) {
+	root, err := git(funcPkgPath, `rev-parse`, `--show-toplevel`)
+	if err != nil {
+		return nil, nil, err
+	}
+	root = strings.TrimSpace(root)
+
+	absPkgPath, err := filepath.Abs(funcPkgPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	absPkgPath, err = filepath.EvalSymlinks(absPkgPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	rel, err := filepath.Rel(root, absPkgPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dir, err := os.MkdirTemp(``, `scparser-`)
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() {
+		_ = os.RemoveAll(dir)
+	}
+
+	archive, err := git(root, `archive`, `--format=tar`, rev)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+
+	if err := extractTar(strings.NewReader(archive), dir); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+
+	m, err := newModuleParser(filepath.Join(dir, rel), opts)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+
+	funcObj, err := m.lookupFunction(funcName)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+
+	p := newParser(m, opts)
+	p.process(funcObj, rootDepth(opts))
+
+	return p, cleanup, nil
+}
+
+// git runs the git command with the given arguments in the directory and returns its output
+func git(dir string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(`git`, append([]string{`-C`, dir}, args...)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, ` `), err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.String(), nil
+}
+
+// extractTar extracts the directories and regular files of the tar archive into the directory
+func extractTar(r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		path := filepath.Join(dir, filepath.FromSlash(hdr.Name))
+		if !strings.HasPrefix(path, filepath.Clean(dir)+string(filepath.Separator)) {
+			return fmt.Errorf("invalid path in archive: %s", hdr.Name)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				return err
+			}
+			content, err := io.ReadAll(tr)
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(path, content, 0o644); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package scparser
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// leadingComments extracts the comment groups directly preceding the function declaration which are separated
+// from it by a blank line, so they are not its doc comments, followed by a blank line. It returns an empty string
+// if there are none.
+func (p *parser) leadingComments(fset *token.FileSet, file *ast.File, fn *ast.FuncDecl) (string, error) {
+	cmap, ok := p.commentMaps[file]
+	if !ok {
+		cmap = ast.NewCommentMap(fset, file, file.Comments)
+		p.commentMaps[file] = cmap
+	}
+
+	start := fn.Pos()
+	if fn.Doc != nil {
+		start = fn.Doc.Pos()
+	}
+
+	var first, last *ast.CommentGroup
+	for _, group := range cmap[fn] {
+		if group == fn.Doc || group.End() >= start {
+			continue
+		}
+		if first == nil || group.Pos() < first.Pos() {
+			first = group
+		}
+		if last == nil || group.End() > last.End() {
+			last = group
+		}
+	}
+	if first == nil {
+		return "", nil
+	}
+
+	src, err := p.extractSourceRange(fset, first.Pos(), last.End(), nil)
+	if err != nil {
+		return "", err
+	}
+
+	return src + "\n", nil
+}
@@ -0,0 +1,193 @@
+package scparser
+
+import (
+	"go/ast"
+	"go/types"
+	"sort"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// ParseCompilable retrieves the source code of the specified function and its underlying functions like
+// ParseWithOptions, and returns it as the content of a Go file per package, by import path. Each file starts with
+// the package clause and the imports used by its declarations, followed by the extracted functions and every
+// declaration of the package they refer to, so the file can be type checked on its own, e.g. by `go vet`.
+// The declarations referenced by the added ones are added as well, and the methods of the included types which
+// implement the included interfaces of any of the packages. Options extracting
+// only the signatures of functions, like DocsOnly, or leaving out functions, like MaxBytes, do not give
+// compilable files.
+func ParseCompilable(funcPkgPath, funcName string, opts Options) (map[string]string, error) {
+	m, err := newModuleParser(funcPkgPath, opts)
+	if err != nil {
+		return nil, &ParseError{PkgPath: funcPkgPath, FuncName: funcName, Err: err}
+	}
+
+	files, err := m.ParseCompilable(funcName, opts)
+	if err != nil {
+		return nil, &ParseError{PkgPath: funcPkgPath, FuncName: funcName, Err: err}
+	}
+
+	return files, nil
+}
+
+// ParseCompilable retrieves the source code of the specified function and its underlying functions as the
+// content of a Go file per package, by import path, see the package level ParseCompilable.
+func (m *ModuleParser) ParseCompilable(funcName string, opts Options) (map[string]string, error) {
+	funcObj, err := m.lookupFunction(funcName)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.IncludeImports = true
+	p := newParser(m, opts)
+	p.process(funcObj, rootDepth(opts))
+
+	// The methods added for the interfaces of a package can refer to the declarations of packages processed
+	// before it, so process the packages again until no more declarations are added
+	for added := true; added; {
+		added = false
+		for k, pkg := range p.pkgOrder {
+			if k == 0 && opts.ExcludeRoot {
+				continue
+			}
+
+			n := len(p.functions[pkg])
+			p.processPkgReferences(pkg)
+			added = added || len(p.functions[pkg]) > n
+		}
+	}
+
+	files := make(map[string]string)
+	for k, pkg := range p.pkgOrder {
+		if k == 0 && opts.ExcludeRoot {
+			continue
+		}
+
+		files[pkg.PkgPath] = "package " + pkg.Name + "\n" + p.joinPkgFunctions(pkg)
+	}
+
+	return files, nil
+}
+
+// processPkgReferences adds the declarations of the package referenced by its extracted declarations, which are
+// not included yet, and the methods implementing its included interfaces, until they are all included
+func (p *parser) processPkgReferences(pkg *packages.Package) {
+	for i := 0; i < len(p.functions[pkg]); i++ {
+		p.processReferences(pkg, i)
+
+		// Once the referenced declarations are included, add the methods of the interfaces they need
+		if i == len(p.functions[pkg])-1 {
+			p.processInterfaceMethods(pkg)
+		}
+	}
+}
+
+// processReferences adds the declarations of the package referenced by the extracted declaration at the index
+func (p *parser) processReferences(pkg *packages.Package, i int) {
+	node := p.functions[pkg][i].node
+	if node == nil {
+		return
+	}
+
+	ast.Inspect(node, func(n ast.Node) bool {
+		ident, ok := n.(*ast.Ident)
+		if !ok {
+			return true
+		}
+
+		obj := pkg.TypesInfo.Uses[ident]
+		if obj == nil || obj.Pkg() != pkg.Types {
+			return true
+		}
+
+		switch obj := obj.(type) {
+		case *types.Func:
+			p.processReferencedFunc(pkg, obj.Origin())
+		case *types.TypeName:
+			if obj.Parent() == pkg.Types.Scope() {
+				p.processTypeDecl(obj)
+			}
+		case *types.Const, *types.Var:
+			if obj.Parent() == pkg.Types.Scope() {
+				p.processValueDecl(obj)
+			}
+		}
+
+		return true
+	})
+}
+
+// processInterfaceMethods adds the methods of the included types of the package which implement the methods of
+// the included interfaces of any package, so the types still satisfy them
+func (p *parser) processInterfaceMethods(pkg *packages.Package) {
+	// Visit the types in the order they are declared, so the methods are added deterministically
+	var objs []*types.TypeName
+	for obj := range p.seenTypes {
+		objs = append(objs, obj)
+	}
+	sort.Slice(objs, func(i, j int) bool {
+		if objs[i].Pkg().Path() != objs[j].Pkg().Path() {
+			return objs[i].Pkg().Path() < objs[j].Pkg().Path()
+		}
+		return objs[i].Pos() < objs[j].Pos()
+	})
+
+	var named []*types.Named
+	var ifaces []*types.Interface
+	for _, obj := range objs {
+		if iface, ok := obj.Type().Underlying().(*types.Interface); ok {
+			ifaces = append(ifaces, iface)
+		} else if t, ok := obj.Type().(*types.Named); ok && t.TypeParams().Len() == 0 && obj.Pkg() == pkg.Types {
+			named = append(named, t)
+		}
+	}
+
+	for _, t := range named {
+		for _, iface := range ifaces {
+			if !types.Implements(t, iface) && !types.Implements(types.NewPointer(t), iface) {
+				continue
+			}
+
+			for k := 0; k < iface.NumMethods(); k++ {
+				obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(t), true, pkg.Types, iface.Method(k).Name())
+				if method, ok := obj.(*types.Func); ok {
+					p.processReferencedFunc(pkg, method.Origin())
+				}
+			}
+		}
+	}
+}
+
+// processReferencedFunc adds the declaration of the referenced function or method of the package, without
+// following its underlying functions
+func (p *parser) processReferencedFunc(pkg *packages.Package, funcObj *types.Func) {
+	f, ok := p.funcToFileAndPkg[funcObj]
+	if !ok || p.seen[funcObj] || f.pkg != pkg {
+		return
+	}
+
+	fn, ok := p.varFuncLits[funcObj]
+	if !ok {
+		fn = declOf(f, funcObj)
+	}
+	if fn == nil {
+		return
+	}
+
+	var recv string
+	if sig := funcObj.Type().(*types.Signature); sig.Recv() != nil {
+		if named := namedType(sig.Recv().Type()); named != nil {
+			recv = named.Obj().Name()
+		}
+	}
+
+	name := qualifiedName(pkg, fn)
+	src := p.render(name, func() (string, error) {
+		return p.extractFunction(pkg.Fset, fn)
+	})
+
+	pos := pkg.Fset.Position(fn.Pos())
+	if p.addFunction(pkg, function{decl: fn, name: name, start: pos, end: pkg.Fset.Position(fn.End()), src: src, recv: recv, node: fn}) {
+		p.seen[funcObj] = true
+	}
+}
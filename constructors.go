@@ -0,0 +1,78 @@
+package scparser
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"strings"
+)
+
+// ParseConstructors retrieves the source code of the constructors of the named type, the functions of its
+// package returning the type or a pointer to it, and their underlying functions within the Go module packages.
+// The type is given by name in the root package, or as `pkg.Type` where pkg is a package name or import path.
+func ParseConstructors(funcPkgPath, typeName string, opts Options) (string, error) {
+	m, err := newModuleParser(funcPkgPath, opts)
+	if err != nil {
+		return "", err
+	}
+
+	return m.ParseConstructors(typeName, opts)
+}
+
+// ParseConstructors retrieves the source code of the constructors of the named type and their underlying functions.
+// The constructors are processed as roots in declaration order, sharing one set of processed functions.
+func (m *ModuleParser) ParseConstructors(typeName string, opts Options) (string, error) {
+	if !strings.Contains(typeName, `.`) {
+		typeName = m.rootPkgPath + `.` + typeName
+	}
+
+	named, ok := m.lookupType(typeName).(*types.Named)
+	if !ok {
+		return "", fmt.Errorf("type %s not found in the go.mod packages", typeName)
+	}
+
+	roots := m.constructors(named)
+	if len(roots) == 0 {
+		return "", fmt.Errorf("no constructors found for type %s", typeName)
+	}
+
+	p := newParser(m, opts)
+	p.processRoots(roots, rootDepth(opts))
+
+	return p.toString(opts.ExcludeRoot, opts.CodeOnly), nil
+}
+
+// constructors returns the functions in the package of the named type
+// with a result assignable to the type or a pointer to it, in declaration order
+func (m *ModuleParser) constructors(named *types.Named) []*types.Func {
+	pkg, ok := m.pkgByPath[named.Obj().Pkg().Path()]
+	if !ok {
+		return nil
+	}
+
+	var roots []*types.Func
+	for _, file := range pkg.Syntax {
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Name == nil || fn.Recv != nil {
+				continue
+			}
+
+			obj, ok := pkg.TypesInfo.ObjectOf(fn.Name).(*types.Func)
+			if !ok {
+				continue
+			}
+			sig := obj.Type().(*types.Signature)
+
+			for i := 0; i < sig.Results().Len(); i++ {
+				res := sig.Results().At(i).Type()
+				if types.AssignableTo(res, named) || types.AssignableTo(res, types.NewPointer(named)) {
+					roots = append(roots, obj)
+					break
+				}
+			}
+		}
+	}
+
+	return roots
+}
@@ -0,0 +1,41 @@
+package scparser
+
+import "context"
+
+// ParseContext is like ParseWithOptions, but stops loading the packages and processing further functions
+// when the context is done. A *ParseError is returned if the module or function can not be found or the
+// loading is canceled. If the context is done during the processing, the functions processed so far are
+// returned together with the error of the context.
+func ParseContext(ctx context.Context, funcPkgPath, funcName string, opts Options) (string, error) {
+	m, err := newModuleParserContext(ctx, funcPkgPath, opts)
+	if err != nil {
+		return "", &ParseError{PkgPath: funcPkgPath, FuncName: funcName, Err: err}
+	}
+
+	funcObj, err := m.lookupFunction(funcName)
+	if err != nil {
+		return "", &ParseError{PkgPath: funcPkgPath, FuncName: funcName, Err: err}
+	}
+
+	p := newParser(m, opts)
+	p.ctx = ctx
+	p.process(funcObj, rootDepth(opts))
+
+	return p.toString(opts.ExcludeRoot, opts.CodeOnly), ctx.Err()
+}
+
+// ParseContext retrieves the source code of the specified function and its underlying functions, stopping
+// when the context is done. The functions processed until then are returned together with the error of the
+// context.
+func (m *ModuleParser) ParseContext(ctx context.Context, funcName string, opts Options) (string, error) {
+	funcObj, err := m.lookupFunction(funcName)
+	if err != nil {
+		return "", err
+	}
+
+	p := newParser(m, opts)
+	p.ctx = ctx
+	p.process(funcObj, rootDepth(opts))
+
+	return p.toString(opts.ExcludeRoot, opts.CodeOnly), ctx.Err()
+}
@@ -0,0 +1,100 @@
+package scparser
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+// processReferencedDecls adds the declarations of the package level types, constants and variables of the
+// go mod packages used in the extracted part of a function to the output
+func (p *parser) processReferencedDecls(info *types.Info, node ast.Node) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		ident, ok := n.(*ast.Ident)
+		if !ok {
+			return true
+		}
+
+		obj := info.Uses[ident]
+		if obj == nil || obj.Pkg() == nil || obj.Parent() != obj.Pkg().Scope() {
+			return true
+		}
+
+		switch obj := obj.(type) {
+		case *types.TypeName:
+			p.processTypeDecl(obj)
+		case *types.Const, *types.Var:
+			p.processValueDecl(obj)
+		}
+
+		return true
+	})
+}
+
+// processValueDecl adds the declaration of the package level constant or variable to the output, if it is
+// declared in a go mod package. Grouped constants are included with their whole group, as their values
+// may depend on the specs before them, e.g. through iota.
+func (p *parser) processValueDecl(obj types.Object) {
+	if p.seenValues[obj] {
+		return
+	}
+
+	for _, pkg := range p.pkgs {
+		if pkg.PkgPath != obj.Pkg().Path() {
+			continue
+		}
+
+		for _, file := range pkg.Syntax {
+			for _, decl := range file.Decls {
+				gd, ok := decl.(*ast.GenDecl)
+				if !ok || (gd.Tok != token.CONST && gd.Tok != token.VAR) {
+					continue
+				}
+
+				for _, spec := range gd.Specs {
+					vs, ok := spec.(*ast.ValueSpec)
+					if !ok || !declaresObject(pkg.TypesInfo, vs, obj) {
+						continue
+					}
+
+					var node ast.Node = vs
+					specs := []ast.Spec{vs}
+					if gd.Tok == token.CONST {
+						node, specs = gd, gd.Specs
+					}
+
+					name := obj.Pkg().Path() + `.` + obj.Name()
+					src := p.render(name, func() (string, error) {
+						if gd.Tok == token.CONST {
+							return p.extractSourceCode(pkg.Fset, gd, gd.Doc)
+						}
+						return p.specSource(pkg.Fset, gd, vs)
+					})
+
+					pos := pkg.Fset.Position(node.Pos())
+					if p.addFunction(pkg, function{name: name, start: pos, end: pkg.Fset.Position(node.End()), src: src, node: node}) {
+						for _, spec := range specs {
+							for _, ident := range spec.(*ast.ValueSpec).Names {
+								if def := pkg.TypesInfo.Defs[ident]; def != nil {
+									p.seenValues[def] = true
+								}
+							}
+						}
+					}
+					return
+				}
+			}
+		}
+	}
+}
+
+// declaresObject reports whether the value spec declares the object
+func declaresObject(info *types.Info, vs *ast.ValueSpec, obj types.Object) bool {
+	for _, ident := range vs.Names {
+		if info.Defs[ident] == obj {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,77 @@
+package scparser
+
+import (
+	"bytes"
+	"go/format"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// dedupBySource leaves out the functions of the package of which the normalized signature and body are identical
+// to those of a function processed earlier, in any package. The function kept gets an `// also:` comment
+// listing the qualified names of the functions left out. Root functions are never left out.
+func (p *parser) dedupBySource(pkg *packages.Package, functions []function) []function {
+	if !p.opts.DedupBySource {
+		return functions
+	}
+
+	// Group the functions of all packages by body, in the order they were processed
+	first := make(map[string]string)
+	also := make(map[string][]string)
+	for _, other := range p.pkgOrder {
+		for _, fn := range p.functions[other] {
+			hash := p.bodyHash(other, fn)
+			if hash == `` {
+				continue
+			}
+			if _, ok := first[hash]; !ok {
+				first[hash] = fn.name
+				continue
+			}
+			if !fn.root {
+				also[first[hash]] = append(also[first[hash]], fn.name)
+			}
+		}
+	}
+
+	var result []function
+	for _, fn := range functions {
+		hash := p.bodyHash(pkg, fn)
+		if hash != `` && first[hash] != fn.name && !fn.root {
+			continue
+		}
+
+		if names := also[fn.name]; len(names) > 0 {
+			fn.src += "// also: " + strings.Join(names, `, `) + "\n"
+		}
+		result = append(result, fn)
+	}
+
+	return result
+}
+
+// bodyHash returns the ContentHash of the gofmt normalized signature and body of the function, without its name
+// and receiver, or an empty string for declarations without a body
+func (p *parser) bodyHash(pkg *packages.Package, fn function) string {
+	if fn.decl == nil || fn.decl.Body == nil {
+		return ``
+	}
+	if hash, ok := p.bodyHashes[fn.decl]; ok {
+		return hash
+	}
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, pkg.Fset, fn.decl.Type); err != nil {
+		return ``
+	}
+	buf.WriteString("\n")
+	if err := format.Node(&buf, pkg.Fset, fn.decl.Body); err != nil {
+		return ``
+	}
+
+	hash := ContentHash(buf.String())
+	p.bodyHashes[fn.decl] = hash
+
+	return hash
+}
@@ -0,0 +1,71 @@
+package scparser
+
+import (
+	"go/types"
+	"strconv"
+)
+
+// recordCall records the call of the underlying function by the function being processed
+func (p *parser) recordCall(funcObj *types.Func) {
+	if p.caller == `` {
+		return
+	}
+	if _, ok := p.funcToFileAndPkg[funcObj]; !ok {
+		return
+	}
+
+	call := [2]string{p.caller, funcObj.FullName()}
+	if p.seenCalls[call] {
+		return
+	}
+	p.seenCalls[call] = true
+	p.calls = append(p.calls, call)
+}
+
+// shortestDepths returns the shortest depth at which each function is reached from the root functions
+// through the recorded calls, by qualified name, the root functions being at depth 1
+func (p *parser) shortestDepths() map[string]int {
+	callees := make(map[string][]string)
+	for _, call := range p.calls {
+		callees[call[0]] = append(callees[call[0]], call[1])
+	}
+
+	depths := make(map[string]int)
+	var queue []string
+	for _, root := range p.roots {
+		if _, ok := depths[root]; !ok {
+			depths[root] = 1
+			queue = append(queue, root)
+		}
+	}
+
+	for i := 0; i < len(queue); i++ {
+		for _, callee := range callees[queue[i]] {
+			if _, ok := depths[callee]; !ok {
+				depths[callee] = depths[queue[i]] + 1
+				queue = append(queue, callee)
+			}
+		}
+	}
+
+	return depths
+}
+
+// annotateDepths returns the functions with a `// depth=N` comment above each function declaration
+// if AnnotateDepth is set
+func (p *parser) annotateDepths(functions []function) []function {
+	if !p.opts.AnnotateDepth {
+		return functions
+	}
+
+	depths := p.shortestDepths()
+	annotated := make([]function, len(functions))
+	for i, fn := range functions {
+		if depth, ok := depths[fn.name]; ok && fn.decl != nil {
+			fn.src = "// depth=" + strconv.Itoa(depth) + "\n" + fn.src
+		}
+		annotated[i] = fn
+	}
+
+	return annotated
+}
@@ -0,0 +1,113 @@
+package scparser
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"strings"
+)
+
+// directivePrefix is the prefix of directive comments in the doc comments of functions, e.g. `//scparser:group auth`
+const directivePrefix = `//scparser:`
+
+// directive is a scparser directive in the doc comments of a function
+type directive struct {
+	// name is the name of the directive, e.g. `group`
+	name string
+
+	// value is the argument of the directive, e.g. `auth`
+	value string
+
+	// obj is the function the directive belongs to
+	obj *types.Func
+}
+
+// parseDirectives parses the scparser directives in the doc comments, one per argument
+func parseDirectives(doc *ast.CommentGroup) []directive {
+	if doc == nil {
+		return nil
+	}
+
+	var directives []directive
+	for _, c := range doc.List {
+		if !strings.HasPrefix(c.Text, directivePrefix) {
+			continue
+		}
+
+		fields := strings.Fields(strings.TrimPrefix(c.Text, directivePrefix))
+		if len(fields) == 0 {
+			continue
+		}
+		for _, value := range fields[1:] {
+			directives = append(directives, directive{name: fields[0], value: value})
+		}
+	}
+
+	return directives
+}
+
+// ParseGroup retrieves the source code of every function tagged with the given group, using a
+// `//scparser:group <name>` doc comment, and their underlying functions within the Go module packages.
+func ParseGroup(funcPkgPath, group string, opts Options) (string, error) {
+	m, err := newModuleParser(funcPkgPath, opts)
+	if err != nil {
+		return "", err
+	}
+
+	return m.ParseGroup(group, opts)
+}
+
+// ParseGroup retrieves the source code of every function tagged with the given group and their underlying functions.
+// The tagged functions are processed as roots in declaration order, sharing one set of processed functions.
+func (m *ModuleParser) ParseGroup(group string, opts Options) (string, error) {
+	var roots []*types.Func
+	for _, d := range m.directives {
+		if d.name == `group` && d.value == group {
+			roots = append(roots, d.obj)
+		}
+	}
+
+	if len(roots) == 0 {
+		return "", fmt.Errorf("no functions found in group %s", group)
+	}
+
+	p := newParser(m, opts)
+	p.processRoots(roots, rootDepth(opts))
+
+	return p.toString(opts.ExcludeRoot, opts.CodeOnly), nil
+}
+
+// ParseByID retrieves the source code of the function carrying the given stable ID, using a
+// `//scparser:id <id>` doc comment, and its underlying functions within the Go module packages.
+// Unlike function names, the ID stays the same when the function is renamed.
+func ParseByID(funcPkgPath, id string, opts Options) (string, error) {
+	m, err := newModuleParser(funcPkgPath, opts)
+	if err != nil {
+		return "", err
+	}
+
+	return m.ParseByID(id, opts)
+}
+
+// ParseByID retrieves the source code of the function carrying the given stable ID and its underlying functions.
+func (m *ModuleParser) ParseByID(id string, opts Options) (string, error) {
+	var root *types.Func
+	for _, d := range m.directives {
+		if d.name != `id` || d.value != id {
+			continue
+		}
+		if root != nil {
+			return "", fmt.Errorf("multiple functions found with id %s", id)
+		}
+		root = d.obj
+	}
+
+	if root == nil {
+		return "", fmt.Errorf("no function found with id %s", id)
+	}
+
+	p := newParser(m, opts)
+	p.process(root, rootDepth(opts))
+
+	return p.toString(opts.ExcludeRoot, opts.CodeOnly), nil
+}
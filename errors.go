@@ -0,0 +1,37 @@
+package scparser
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrFunctionNotFound is returned if the function is not found in the root package
+	ErrFunctionNotFound = errors.New(`function not found`)
+
+	// ErrAmbiguousFunction is returned if an unqualified method name matches the methods of several types,
+	// qualify it with the receiver type as `Type.Method` instead
+	ErrAmbiguousFunction = errors.New(`ambiguous function`)
+
+	// ErrGoModNotFound is returned if the package directory has no usable go.mod file
+	ErrGoModNotFound = errors.New(`go.mod not found`)
+
+	// ErrPackageLoad is returned if the packages of the module can not be loaded
+	ErrPackageLoad = errors.New(`package load failed`)
+)
+
+// ParseError is the error of parsing a function, carrying the package path and function name that failed.
+// Use errors.Is to check for ErrFunctionNotFound, ErrAmbiguousFunction, ErrGoModNotFound or ErrPackageLoad.
+type ParseError struct {
+	PkgPath  string
+	FuncName string
+	Err      error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("parse %s in %s: %v", e.FuncName, e.PkgPath, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
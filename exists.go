@@ -0,0 +1,33 @@
+package scparser
+
+import "errors"
+
+// Exists loads the go.mod packages and reports whether the function is declared in the root package, without
+// extracting any source code. A *ParseError is returned if the packages can not be loaded or an unqualified
+// method name is ambiguous.
+func Exists(funcPkgPath, funcName string) (bool, error) {
+	m, err := newModuleParser(funcPkgPath, Options{})
+	if err != nil {
+		return false, &ParseError{PkgPath: funcPkgPath, FuncName: funcName, Err: err}
+	}
+
+	ok, err := m.Exists(funcName)
+	if err != nil {
+		return false, &ParseError{PkgPath: funcPkgPath, FuncName: funcName, Err: err}
+	}
+
+	return ok, nil
+}
+
+// Exists reports whether the function is declared in the root package.
+// An error wrapping ErrAmbiguousFunction is returned if an unqualified method name matches several methods.
+func (m *ModuleParser) Exists(funcName string) (bool, error) {
+	if _, err := m.lookupFunction(funcName); err != nil {
+		if errors.Is(err, ErrFunctionNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,81 @@
+package scparser
+
+import (
+	"go/types"
+	"sort"
+	"strings"
+)
+
+// processExternalFunction records a called function which is declared outside the loaded go.mod packages
+func (p *parser) processExternalFunction(fn *types.Func) {
+	if p.seenExternal[fn] {
+		return
+	}
+
+	// Abstract methods of go mod interfaces have no source code, but are not external
+	if _, ok := p.pkgByPath[fn.Pkg().Path()]; ok {
+		return
+	}
+
+	p.seenExternal[fn] = true
+	p.external = append(p.external, fn)
+}
+
+// externalStubs formats the signatures of the external functions, each on its own comment line
+func (p *parser) externalStubs() string {
+	result := "\n"
+	for _, fn := range p.external {
+		result += p.externalStub(fn) + "\n"
+	}
+
+	return result
+}
+
+// externalStub formats the signature of an external function as a comment line,
+// e.g. `// external: rsc.io/quote v1.5.2 — quote.Hello() string`
+func (p *parser) externalStub(fn *types.Func) string {
+	qualifier := func(pkg *types.Package) string {
+		return pkg.Name()
+	}
+	sig := strings.TrimPrefix(types.ObjectString(fn, qualifier), `func `)
+
+	if p.opts.ExternalVersions {
+		if mod, version := p.moduleOf(fn.Pkg().Path()); mod != `` {
+			return `// external: ` + mod + ` ` + version + ` — ` + sig
+		}
+	}
+
+	return `// external: ` + sig
+}
+
+// externalCalls formats the qualified names of the external functions as a sorted list of comment lines,
+// e.g. `// strings.TrimSpace` or `// (*net/http.Client).Do`
+func (p *parser) externalCalls() string {
+	names := make([]string, 0, len(p.external))
+	for _, fn := range p.external {
+		names = append(names, fn.FullName())
+	}
+	sort.Strings(names)
+
+	result := "\n// External calls:\n"
+	for _, name := range names {
+		result += `// ` + name + "\n"
+	}
+
+	return result
+}
+
+// moduleOf returns the path and version of the required go.mod module providing the package
+func (m *ModuleParser) moduleOf(pkgPath string) (string, string) {
+	var mod string
+	for path := range m.versions {
+		if (pkgPath == path || strings.HasPrefix(pkgPath, path+`/`)) && len(path) > len(mod) {
+			mod = path
+		}
+	}
+	if mod == `` {
+		return ``, ``
+	}
+
+	return mod, m.versions[mod]
+}
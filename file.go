@@ -0,0 +1,44 @@
+package scparser
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+)
+
+// ParseFile is like ParseWithDepth, but looks up the root function among the declarations of the given file only.
+// Just the package containing the file is loaded, its imports are type checked from their export data, so only the
+// underlying functions within that package are followed. This avoids loading every package of a large module.
+func ParseFile(filePath, funcName string, depth int) (string, error) {
+	if depth < 0 {
+		return "", fmt.Errorf("invalid depth %d: must not be negative", depth)
+	}
+
+	filename, err := filepath.Abs(filePath)
+	if err != nil {
+		return "", &ParseError{PkgPath: filePath, FuncName: funcName, Err: fmt.Errorf("%w: %w", ErrPackageLoad, err)}
+	}
+
+	dir, err := moduleDir(filepath.Dir(filename))
+	if err != nil {
+		return "", &ParseError{PkgPath: filePath, FuncName: funcName, Err: err}
+	}
+
+	m, err := initialize(context.Background(), dir, Options{}, `file=`+filename)
+	if err != nil {
+		return "", &ParseError{PkgPath: filePath, FuncName: funcName, Err: err}
+	}
+	m.rootPkgPath = m.pkgPathOfDir(filepath.Dir(filename))
+	m.rootFile = filename
+
+	opts := Options{MaxDepth: depth + 1}
+	funcObj, err := m.lookupFunction(funcName)
+	if err != nil {
+		return "", &ParseError{PkgPath: filePath, FuncName: funcName, Err: err}
+	}
+
+	p := newParser(m, opts)
+	p.process(funcObj, rootDepth(opts))
+
+	return p.toString(opts.ExcludeRoot, opts.CodeOnly), nil
+}
@@ -2,9 +2,9 @@ module github.com/elwint/scparser
 
 go 1.20
 
-require golang.org/x/tools v0.12.0
-
 require (
-	golang.org/x/mod v0.12.0 // indirect
-	golang.org/x/sys v0.11.0 // indirect
+	golang.org/x/mod v0.12.0
+	golang.org/x/tools v0.12.0
 )
+
+require golang.org/x/sys v0.11.0 // indirect
@@ -0,0 +1,15 @@
+package scparser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// ContentHash returns the hex encoded SHA-256 digest of the output of one of the Parse functions.
+// The output is deterministic for identical sources and options, so the digest can be used as
+// a cache key and to detect when extracting again is necessary after the sources changed.
+func ContentHash(output string) string {
+	sum := sha256.Sum256([]byte(output))
+
+	return hex.EncodeToString(sum[:])
+}
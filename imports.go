@@ -0,0 +1,64 @@
+package scparser
+
+import (
+	"go/ast"
+	"go/types"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// pkgImports returns the import declaration of the imports used by the functions of the package, sorted by
+// import path, or an empty string if IncludeImports is not set or no imports are used
+func (p *parser) pkgImports(pkg *packages.Package, functions []function) string {
+	if !p.opts.IncludeImports {
+		return ""
+	}
+
+	paths := make(map[string]string)
+	for _, fn := range functions {
+		if fn.node == nil {
+			continue
+		}
+
+		ast.Inspect(fn.node, func(n ast.Node) bool {
+			ident, ok := n.(*ast.Ident)
+			if !ok {
+				return true
+			}
+
+			if pkgName, ok := pkg.TypesInfo.Uses[ident].(*types.PkgName); ok {
+				spec := strconv.Quote(pkgName.Imported().Path())
+				if pkgName.Name() != pkgName.Imported().Name() {
+					spec = pkgName.Name() + ` ` + spec
+				}
+				paths[spec] = pkgName.Imported().Path()
+			}
+
+			return true
+		})
+	}
+
+	if len(paths) == 0 {
+		return ""
+	}
+
+	specs := make([]string, 0, len(paths))
+	for spec := range paths {
+		specs = append(specs, spec)
+	}
+	sort.Slice(specs, func(i, j int) bool {
+		if paths[specs[i]] != paths[specs[j]] {
+			return paths[specs[i]] < paths[specs[j]]
+		}
+		return specs[i] < specs[j]
+	})
+
+	if len(specs) == 1 {
+		return "import " + specs[0] + "\n"
+	}
+
+	return "import (\n\t" + strings.Join(specs, "\n\t") + "\n)\n"
+}
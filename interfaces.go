@@ -0,0 +1,278 @@
+package scparser
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// ParseImplementors retrieves the source code of the methods of every go.mod type implementing the
+// interface with the given import path and name, and their underlying functions. The methods are
+// processed as roots sharing one set of processed functions, so common functions appear only once.
+func (m *ModuleParser) ParseImplementors(interfacePath, interfaceName string, opts Options) (string, error) {
+	iface, err := m.lookupInterface(interfacePath, interfaceName)
+	if err != nil {
+		return "", err
+	}
+
+	var roots []*types.Func
+	for i := 0; i < iface.NumMethods(); i++ {
+		roots = append(roots, m.implementations(iface, iface.Method(i).Name())...)
+	}
+
+	p := newParser(m, opts)
+	p.processRoots(roots, rootDepth(opts))
+
+	return p.toString(opts.ExcludeRoot, opts.CodeOnly), nil
+}
+
+// lookupInterface finds the interface with the given name in the package with the given import path,
+// which is either one of the go mod packages or imported by one of them
+func (m *ModuleParser) lookupInterface(interfacePath, interfaceName string) (*types.Interface, error) {
+	for _, pkg := range m.pkgs {
+		if pkg.Types == nil {
+			continue
+		}
+
+		candidates := append([]*types.Package{pkg.Types}, pkg.Types.Imports()...)
+		for _, candidate := range candidates {
+			if candidate.Path() != interfacePath {
+				continue
+			}
+
+			obj, ok := candidate.Scope().Lookup(interfaceName).(*types.TypeName)
+			if !ok {
+				return nil, fmt.Errorf("interface %s not found in package %s", interfaceName, interfacePath)
+			}
+
+			iface, ok := obj.Type().Underlying().(*types.Interface)
+			if !ok {
+				return nil, fmt.Errorf("%s.%s is not an interface", interfacePath, interfaceName)
+			}
+
+			return iface, nil
+		}
+	}
+
+	return nil, fmt.Errorf("package %s not found in the go.mod packages or their imports", interfacePath)
+}
+
+// processConstraintMethod processes a method called on a value of a type parameter.
+// With ResolveInterfaces, the method of each go mod type satisfying the constraint is processed,
+// otherwise the declaration of the constraint is included.
+func (p *parser) processConstraintMethod(tp *types.TypeParam, methodName string, depth int) {
+	if p.opts.ResolveInterfaces {
+		if iface, ok := tp.Constraint().Underlying().(*types.Interface); ok {
+			p.processImplementations(iface, methodName, depth)
+		}
+		return
+	}
+
+	// Anonymous constraints are already part of the function signature
+	if named, ok := tp.Constraint().(*types.Named); ok {
+		p.processTypeDecl(named.Obj())
+	}
+}
+
+// abstractMethodOf returns the interface declaring the function if it is an abstract method, or nil otherwise
+func abstractMethodOf(fn *types.Func) *types.Interface {
+	sig, ok := fn.Type().(*types.Signature)
+	if !ok || sig.Recv() == nil {
+		return nil
+	}
+
+	iface, _ := sig.Recv().Type().Underlying().(*types.Interface)
+
+	return iface
+}
+
+// processImplementations processes the method with the given name of every go mod type implementing the interface
+func (p *parser) processImplementations(iface *types.Interface, methodName string, depth int) {
+	for _, fn := range p.implementations(iface, methodName) {
+		p.followFunction(fn, depth)
+	}
+}
+
+// implementations returns the method with the given name of every go mod type implementing the interface
+func (m *ModuleParser) implementations(iface *types.Interface, methodName string) []*types.Func {
+	var methods []*types.Func
+	for _, pkg := range m.pkgs {
+		if pkg.Types == nil {
+			continue
+		}
+
+		scope := pkg.Types.Scope()
+		for _, name := range scope.Names() {
+			obj, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || obj.IsAlias() {
+				continue
+			}
+
+			// Skip interfaces and generic types, which have no method bodies of their own
+			named, ok := obj.Type().(*types.Named)
+			if !ok || types.IsInterface(named) || named.TypeParams().Len() > 0 {
+				continue
+			}
+
+			// Check the value type first, and the pointer type for methods with a pointer receiver
+			for _, typ := range []types.Type{named, types.NewPointer(named)} {
+				if !types.Implements(typ, iface) {
+					continue
+				}
+
+				method, _, _ := types.LookupFieldOrMethod(typ, true, pkg.Types, methodName)
+				if fn, ok := method.(*types.Func); ok {
+					methods = append(methods, fn)
+				}
+				break
+			}
+		}
+	}
+
+	return methods
+}
+
+// interfaceOrder is an interface together with the declaration order of its methods
+type interfaceOrder struct {
+	iface *types.Interface
+
+	// rank is the index of each method name in the declaration order
+	rank map[string]int
+}
+
+// interfaceOrder looks up the interface referenced as pkg.Interface and the declaration order of its methods,
+// or returns nil if it is not found
+func (m *ModuleParser) interfaceOrder(typeName string) *interfaceOrder {
+	named, ok := m.lookupType(typeName).(*types.Named)
+	if !ok || !types.IsInterface(named) {
+		fmt.Println("Warning: InterfaceOrder interface not found:", typeName)
+		return nil
+	}
+
+	rank := make(map[string]int)
+	for _, name := range m.declaredMethods(named) {
+		if _, ok := rank[name]; !ok {
+			rank[name] = len(rank)
+		}
+	}
+
+	return &interfaceOrder{iface: named.Underlying().(*types.Interface), rank: rank}
+}
+
+// declaredMethods returns the method names of the named interface in declaration order, with the methods of
+// embedded interfaces in their place. Interfaces without loaded source code fall back to the order of go/types.
+func (m *ModuleParser) declaredMethods(named *types.Named) []string {
+	pkg, spec := m.typeSpec(named.Obj())
+	var it *ast.InterfaceType
+	if spec != nil {
+		it, _ = spec.Type.(*ast.InterfaceType)
+	}
+	if it == nil {
+		iface := named.Underlying().(*types.Interface)
+		names := make([]string, 0, iface.NumMethods())
+		for i := 0; i < iface.NumMethods(); i++ {
+			names = append(names, iface.Method(i).Name())
+		}
+		return names
+	}
+
+	var names []string
+	for _, field := range it.Methods.List {
+		if len(field.Names) > 0 {
+			for _, name := range field.Names {
+				names = append(names, name.Name)
+			}
+			continue
+		}
+
+		if embedded, ok := pkg.TypesInfo.TypeOf(field.Type).(*types.Named); ok && types.IsInterface(embedded) {
+			names = append(names, m.declaredMethods(embedded)...)
+		}
+	}
+
+	return names
+}
+
+// typeSpec finds the declaration of the named type in the go mod packages
+func (m *ModuleParser) typeSpec(obj *types.TypeName) (*packages.Package, *ast.TypeSpec) {
+	if obj.Pkg() == nil {
+		return nil, nil
+	}
+
+	pkg, ok := m.pkgByPath[obj.Pkg().Path()]
+	if !ok {
+		return nil, nil
+	}
+
+	for _, file := range pkg.Syntax {
+		for _, decl := range file.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.TYPE {
+				continue
+			}
+
+			for _, spec := range gd.Specs {
+				if ts, ok := spec.(*ast.TypeSpec); ok && pkg.TypesInfo.Defs[ts.Name] == obj {
+					return pkg, ts
+				}
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// orderByInterface reorders the methods of each type implementing the InterfaceOrder interface to follow the
+// declaration order of the interface. The methods keep the places they take among the functions of the package.
+func (p *parser) orderByInterface(pkg *packages.Package, functions []function) []function {
+	if p.ifaceOrder == nil {
+		return functions
+	}
+
+	// Collect the places of the interface methods of each implementing receiver type
+	places := make(map[*types.Named][]int)
+	var recvOrder []*types.Named
+	for i, fn := range functions {
+		if fn.decl == nil || fn.decl.Recv == nil {
+			continue
+		}
+		if _, ok := p.ifaceOrder.rank[fn.decl.Name.Name]; !ok {
+			continue
+		}
+
+		sig, ok := pkg.TypesInfo.ObjectOf(fn.decl.Name).Type().(*types.Signature)
+		if !ok || sig.Recv() == nil {
+			continue
+		}
+		named := namedType(sig.Recv().Type())
+		if named == nil || (!types.Implements(named, p.ifaceOrder.iface) && !types.Implements(types.NewPointer(named), p.ifaceOrder.iface)) {
+			continue
+		}
+
+		if _, ok := places[named]; !ok {
+			recvOrder = append(recvOrder, named)
+		}
+		places[named] = append(places[named], i)
+	}
+
+	ordered := append([]function(nil), functions...)
+	for _, named := range recvOrder {
+		methods := make([]function, 0, len(places[named]))
+		for _, i := range places[named] {
+			methods = append(methods, functions[i])
+		}
+		sort.SliceStable(methods, func(a, b int) bool {
+			return p.ifaceOrder.rank[methods[a].decl.Name.Name] < p.ifaceOrder.rank[methods[b].decl.Name.Name]
+		})
+
+		for j, i := range places[named] {
+			ordered[i] = methods[j]
+		}
+	}
+
+	return ordered
+}
@@ -0,0 +1,17 @@
+package scparser
+
+import "testing"
+
+func TestConstraintMethodResolveInterfaces(t *testing.T) {
+	output := parseTest(t, `DescribeRoot`, Options{ResolveInterfaces: true})
+
+	assertContains(t, output, `func Describe[T Namer](x T) string {`, `func (Dog) Name() string { return "dog" }`)
+	assertNotContains(t, output, `type Namer interface {`)
+}
+
+func TestConstraintMethodDeclaration(t *testing.T) {
+	output := parseTest(t, `DescribeRoot`, Options{})
+
+	assertContains(t, output, `func Describe[T Namer](x T) string {`, "// Namer has a name.\ntype Namer interface {")
+	assertNotContains(t, output, `func (Dog) Name()`)
+}
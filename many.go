@@ -0,0 +1,47 @@
+package scparser
+
+import (
+	"fmt"
+	"go/types"
+)
+
+// SourceOfMany retrieves the source code of exactly the given functions, without following their underlying
+// functions, formatted like Parse. The functions are included once each, grouped by package in the order they
+// are given, and ExcludeRoot is ignored. They may come from a separate load of the packages, e.g. by an SSA
+// analysis, in which case they are matched by qualified name. The function returns an error if one of them is
+// not declared in the go.mod packages.
+func (m *ModuleParser) SourceOfMany(fns []*types.Func, opts Options) (string, error) {
+	roots := make([]*types.Func, 0, len(fns))
+	for _, fn := range fns {
+		obj, err := m.declaredFunc(fn)
+		if err != nil {
+			return "", err
+		}
+		roots = append(roots, obj)
+	}
+
+	p := newParser(m, opts)
+	for _, obj := range roots {
+		p.rootSet[obj] = true
+		p.processFunction(obj, 1)
+	}
+
+	return p.toString(false, opts.CodeOnly), nil
+}
+
+// declaredFunc returns the function as declared in the go mod packages
+func (m *ModuleParser) declaredFunc(fn *types.Func) (*types.Func, error) {
+	if _, ok := m.funcToFileAndPkg[fn]; ok {
+		return fn, nil
+	}
+
+	if fn.Pkg() != nil {
+		if pkg, ok := m.pkgByPath[fn.Pkg().Path()]; ok {
+			if _, _, obj := declByName(pkg, fn); obj != nil {
+				return obj, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("function %s not found in the go.mod packages", fn.FullName())
+}
@@ -0,0 +1,97 @@
+package scparser
+
+import (
+	"context"
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// moduleCacheFunction loads the package of the function from the module cache and returns the function as
+// declared there, or nil if the package is not available. The returned function can be processed like
+// a go.mod function, although it is distinct from the function called.
+func (p *parser) moduleCacheFunction(fn *types.Func) *types.Func {
+	// Functions of go mod packages without a declaration, like abstract methods, have no source code to load
+	if fn.Pkg() == nil {
+		return nil
+	}
+	if _, ok := p.pkgByPath[fn.Pkg().Path()]; ok {
+		return nil
+	}
+
+	pkg := p.loadModuleCachePackage(p.ctx, fn.Pkg().Path())
+	if pkg == nil {
+		return nil
+	}
+
+	// Match the declaration by name, as the types of the packages loaded separately are not identical
+	file, fd, obj := declByName(pkg, fn)
+	if obj == nil {
+		return nil
+	}
+
+	// The function is only added to the functions of the parser, not to those of the module
+	p.funcToFileAndPkg[obj] = fileAndPkg{
+		file:     file,
+		pkg:      pkg,
+		exported: fd.Name.IsExported(),
+	}
+
+	return obj
+}
+
+// declByName finds the declaration in the package of the function or method with the same qualified name
+// as the given function, which may be loaded separately. It returns a nil function if there is none.
+func declByName(pkg *packages.Package, fn *types.Func) (*ast.File, *ast.FuncDecl, *types.Func) {
+	name := fn.Origin().FullName()
+	for _, file := range pkg.Syntax {
+		for _, decl := range file.Decls {
+			fd, ok := decl.(*ast.FuncDecl)
+			if !ok || fd.Name == nil || fd.Name.Name != fn.Name() {
+				continue
+			}
+
+			obj, ok := pkg.TypesInfo.ObjectOf(fd.Name).(*types.Func)
+			if !ok || obj.FullName() != name {
+				continue
+			}
+
+			return file, fd, obj
+		}
+	}
+
+	return nil, nil, nil
+}
+
+// loadModuleCachePackage loads the package with the given import path from the module cache, with the context of
+// the parse if not nil. It returns nil for packages of the standard library and packages that can not be loaded.
+// The packages are loaded once, also when parsing concurrently.
+func (m *ModuleParser) loadModuleCachePackage(ctx context.Context, pkgPath string) *packages.Package {
+	m.cacheMu.Lock()
+	defer m.cacheMu.Unlock()
+
+	if pkg, ok := m.cachePkgs[pkgPath]; ok {
+		return pkg
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	cfg := *m.config
+	cfg.Context = ctx
+	cfg.Mode |= packages.NeedModule
+	pkgs, err := packages.Load(&cfg, pkgPath)
+	if ctx.Err() != nil {
+		// Loading again with another context may succeed
+		return nil
+	}
+	m.cachePkgs[pkgPath] = nil
+	if err != nil || len(pkgs) != 1 || pkgs[0].Module == nil || len(pkgs[0].Errors) > 0 {
+		return nil
+	}
+
+	m.cachePkgs[pkgPath] = pkgs[0]
+
+	return pkgs[0]
+}
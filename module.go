@@ -0,0 +1,466 @@
+package scparser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// ModuleParser holds the loaded packages of a Go module, so that multiple functions
+// can be parsed without loading and type checking the module again.
+type ModuleParser struct {
+	// goModPaths are the package paths listed in go.mod, starting with the module path
+	goModPaths []string
+
+	// versions are the required versions of the modules listed in go.mod
+	versions map[string]string
+
+	// pkgs are the loaded go.mod packages
+	pkgs []*packages.Package
+
+	// pkgByPath is a map of import paths to the loaded go.mod packages
+	pkgByPath map[string]*packages.Package
+
+	// funcToFileAndPkg is a map that stores the file and package for each declared function
+	funcToFileAndPkg map[*types.Func]fileAndPkg
+
+	// varFuncs is a map of package level variables to the functions and methods referenced as values
+	// in the composite literals they are initialized with
+	varFuncs map[*types.Var][]*types.Func
+
+	// fieldFuncs is a map of struct fields of function type to the functions and methods assigned to them
+	// in the go mod packages
+	fieldFuncs map[*types.Var][]*types.Func
+
+	// dir is the absolute path of the directory the packages are loaded from
+	dir string
+
+	// config is the configuration the packages are loaded with
+	config *packages.Config
+
+	// sources holds the content of the source files as they were parsed when loading the packages
+	sources *fileSources
+
+	// rootPkgPath is the import path of the package in the given package directory, in which root functions are looked up
+	rootPkgPath string
+
+	// rootFile is the absolute path of the file to which the lookup of root functions is constrained, empty for all
+	// files of the root package
+	rootFile string
+
+	// cachePkgs are the packages loaded from the module cache by import path, nil if they could not be loaded
+	cachePkgs map[string]*packages.Package
+
+	// cacheMu guards cachePkgs, which are loaded while parsing
+	cacheMu sync.Mutex
+
+	// testPkgs are the packages of the main module loaded including their tests, nil until they are needed
+	testPkgs []*packages.Package
+
+	// testMu guards testPkgs, which are loaded while parsing
+	testMu sync.Mutex
+
+	// varFuncLits are the function literals initializing package level variables, which can be used as root,
+	// as function declarations with the name of the variable
+	varFuncLits map[*types.Func]*ast.FuncDecl
+
+	// tests reports whether the packages are loaded including their test files
+	tests bool
+
+	// directives are the scparser directives in the doc comments of the functions, in package path and declaration order
+	directives []directive
+}
+
+// Module is a loaded Go module, see ModuleParser.
+type Module = ModuleParser
+
+// LoadModule is like NewModuleParser, but returns an error wrapping ErrGoModNotFound or ErrPackageLoad instead of
+// panicking if the packages can not be loaded. The packages are loaded and type checked once, so the functions of
+// the module can be parsed with the methods of the Module without loading it again.
+func LoadModule(dir string) (*Module, error) {
+	return newModuleParser(dir, Options{})
+}
+
+// FuncDecl is a function declaration reached by the parser, together with the package
+// and file set it was loaded from.
+type FuncDecl struct {
+	Decl *ast.FuncDecl
+	Pkg  *packages.Package
+	Fset *token.FileSet
+}
+
+// NewModuleParser loads the go.mod packages of the module in the given package directory, without vendoring.
+// The function will panic if the packages can not be loaded.
+func NewModuleParser(funcPkgPath string) *ModuleParser {
+	m, err := newModuleParser(funcPkgPath, Options{})
+	panicOnErr(err)
+
+	return m
+}
+
+// newModuleParser is like NewModuleParser, but returns an error wrapping ErrGoModNotFound or ErrPackageLoad
+// if the packages can not be loaded. The packages are loaded with the Vendor, Tests, BuildTags, GOOS and GOARCH
+// settings of opts.
+func newModuleParser(funcPkgPath string, opts Options) (*ModuleParser, error) {
+	return newModuleParserContext(context.Background(), funcPkgPath, opts)
+}
+
+// newModuleParserContext is like newModuleParser, but stops loading the packages when the context is done
+func newModuleParserContext(ctx context.Context, funcPkgPath string, opts Options) (*ModuleParser, error) {
+	dir, err := filepath.Abs(funcPkgPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrPackageLoad, err)
+	}
+
+	// The package directory may be a subpackage, like the go tool, load the module of the nearest go.mod above it
+	modDir, err := moduleDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := initialize(ctx, modDir, opts)
+	if err != nil {
+		return nil, err
+	}
+	m.rootPkgPath = m.pkgPathOfDir(dir)
+
+	return m, nil
+}
+
+// pkgPathOfDir returns the import path of the go mod package with its files in the directory,
+// or the module path if there is none
+func (m *ModuleParser) pkgPathOfDir(dir string) string {
+	for _, pkg := range m.pkgs {
+		for _, file := range pkg.GoFiles {
+			if filepath.Dir(file) == dir {
+				return pkg.PkgPath
+			}
+		}
+	}
+
+	return m.goModPaths[0]
+}
+
+// Parse retrieves the source code of the specified function and its underlying functions,
+// formatted the same way as the package level Parse.
+func (m *ModuleParser) Parse(funcName string, excludeRoot, codeOnly bool) string {
+	return m.ParseWithOptions(funcName, Options{ExcludeRoot: excludeRoot, CodeOnly: codeOnly})
+}
+
+// ParseWithOptions is like Parse, but takes the output and traversal settings from opts.
+func (m *ModuleParser) ParseWithOptions(funcName string, opts Options) string {
+	return m.traverse(funcName, opts).toString(opts.ExcludeRoot, opts.CodeOnly)
+}
+
+// ParseMany retrieves the combined source code of the specified root functions and their underlying functions,
+// processing the roots in the order given. It panics if one of the functions is not found.
+func (m *ModuleParser) ParseMany(funcNames []string, opts Options) string {
+	roots := make([]*types.Func, 0, len(funcNames))
+	for _, funcName := range funcNames {
+		roots = append(roots, m.findFunction(funcName))
+	}
+
+	p := newParser(m, opts)
+	p.processRoots(roots, rootDepth(opts))
+
+	return p.toString(opts.ExcludeRoot, opts.CodeOnly)
+}
+
+// ParseWithOptionsE is like ParseWithOptions, but also returns the errors of functions that could not be rendered.
+// Such functions are replaced by a placeholder comment in the output, so the rest of the output is still returned.
+// With the Verify option, the returned error includes a *VerifyError if extracted functions do not parse.
+// An error wrapping ErrFunctionNotFound or ErrAmbiguousFunction is returned if the function can not be looked up.
+func (m *ModuleParser) ParseWithOptionsE(funcName string, opts Options) (string, error) {
+	p, err := m.traverseE(funcName, opts)
+	if err != nil {
+		return "", err
+	}
+
+	errs := p.errs
+	if opts.Verify {
+		if err := p.verify(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return p.toString(opts.ExcludeRoot, opts.CodeOnly), errors.Join(errs...)
+}
+
+// ParseWithOptionsHashE is like ParseWithOptionsE, but also returns the ContentHash of the output.
+func (m *ModuleParser) ParseWithOptionsHashE(funcName string, opts Options) (string, string, error) {
+	output, err := m.ParseWithOptionsE(funcName, opts)
+
+	return output, ContentHash(output), err
+}
+
+// FuncDecls returns the declarations of the specified function and its underlying functions,
+// in the same order as they appear in the output of Parse.
+func (m *ModuleParser) FuncDecls(funcName string, excludeRoot bool) []FuncDecl {
+	p := m.traverse(funcName, Options{ExcludeRoot: excludeRoot})
+
+	var decls []FuncDecl
+	for k, pkg := range p.pkgOrder {
+		if k == 0 && excludeRoot {
+			continue
+		}
+		for _, fn := range p.functions[pkg] {
+			decls = append(decls, FuncDecl{
+				Decl: fn.decl,
+				Pkg:  pkg,
+				Fset: pkg.Fset,
+			})
+		}
+	}
+
+	return decls
+}
+
+// ReachablePackages returns the import paths of the packages of the specified function and its underlying
+// functions, in the same order as they appear in the output of Parse. The source code of the functions is
+// not extracted, so options depending on it, such as MaxBytes, have no effect.
+func (m *ModuleParser) ReachablePackages(funcName string, opts Options) ([]string, error) {
+	funcObj, err := m.lookupFunction(funcName)
+	if err != nil {
+		return nil, err
+	}
+
+	p := newParser(m, opts)
+	p.noSource = true
+	p.process(funcObj, rootDepth(opts))
+
+	var paths []string
+	for k, pkg := range p.pkgOrder {
+		if k == 0 && opts.ExcludeRoot {
+			continue
+		}
+		paths = append(paths, pkg.PkgPath)
+	}
+
+	return paths, nil
+}
+
+// traverse processes the specified function and its underlying functions
+func (m *ModuleParser) traverse(funcName string, opts Options) *parser {
+	funcObj := m.findFunction(funcName)
+
+	p := newParser(m, opts)
+	p.process(funcObj, rootDepth(opts))
+
+	return p
+}
+
+// traverseE is like traverse, but returns an error wrapping ErrFunctionNotFound or ErrAmbiguousFunction
+// instead of panicking if the function can not be looked up
+func (m *ModuleParser) traverseE(funcName string, opts Options) (*parser, error) {
+	funcObj, err := m.lookupFunction(funcName)
+	if err != nil {
+		return nil, err
+	}
+
+	p := newParser(m, opts)
+	p.process(funcObj, rootDepth(opts))
+
+	return p, nil
+}
+
+// defaultDepth is the depth functions are processed up to when no MaxDepth is given
+const defaultDepth = 5
+
+// rootDepth returns the depth to process root functions with, MaxDepth or 5 by default (plus one if root is excluded)
+func rootDepth(opts Options) int {
+	depth := opts.MaxDepth
+	if depth == 0 {
+		depth = defaultDepth
+	}
+
+	if opts.ExcludeRoot {
+		return depth + 1
+	}
+
+	return depth
+}
+
+// findFunction searches for the target function with the provided name in the root package.
+// It panics if the function is not found.
+func (m *ModuleParser) findFunction(funcName string) *types.Func {
+	obj, err := m.lookupFunction(funcName)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	return obj
+}
+
+// lookupFunction searches for the target function with the provided name in the root package.
+// The name of a method may be qualified with its receiver type, as `Type.Method` for a method of Type
+// with either receiver or `(*Type).Method` for a method with a pointer receiver. An unqualified name
+// matches the function with that name, or else the method, returning an error wrapping ErrAmbiguousFunction
+// that lists the candidates if methods of several types have the name.
+func (m *ModuleParser) lookupFunction(funcName string) (*types.Func, error) {
+	recvName, pointer, name := splitFuncName(funcName)
+
+	var methods []*types.Func
+	for _, pkg := range m.pkgs {
+		if pkg.PkgPath != m.rootPkgPath {
+			continue
+		}
+
+		for _, file := range pkg.Syntax {
+			if !m.inRootFile(pkg.Fset, file) {
+				continue
+			}
+
+			for _, decl := range file.Decls {
+				fn, ok := decl.(*ast.FuncDecl)
+				if !ok || fn.Name == nil || fn.Name.Name != name {
+					continue
+				}
+				if recvName != `` && !hasRecv(fn, recvName, pointer) {
+					continue
+				}
+
+				obj, ok := pkg.TypesInfo.ObjectOf(fn.Name).(*types.Func)
+				if !ok {
+					continue
+				}
+				if fn.Recv != nil && recvName == `` {
+					methods = append(methods, obj)
+					continue
+				}
+
+				return obj, nil
+			}
+		}
+
+		if recvName == `` {
+			if obj := m.varFuncLit(pkg, funcName); obj != nil && m.inRootFile(pkg.Fset, m.funcToFileAndPkg[obj].file) {
+				return obj, nil
+			}
+		}
+	}
+
+	switch len(methods) {
+	case 0:
+	case 1:
+		return methods[0], nil
+	default:
+		candidates := make([]string, len(methods))
+		for i, method := range methods {
+			candidates[i] = method.FullName()
+		}
+		return nil, fmt.Errorf("%w: %s matches %s", ErrAmbiguousFunction, funcName, strings.Join(candidates, `, `))
+	}
+
+	if m.rootFile != `` {
+		return nil, fmt.Errorf("%w: %s in file %s", ErrFunctionNotFound, funcName, m.rootFile)
+	}
+
+	return nil, fmt.Errorf("%w: %s in package %s", ErrFunctionNotFound, funcName, m.rootPkgPath)
+}
+
+// inRootFile reports whether root functions are looked up in the file
+func (m *ModuleParser) inRootFile(fset *token.FileSet, file *ast.File) bool {
+	return m.rootFile == `` || filepath.Clean(fset.Position(file.Pos()).Filename) == m.rootFile
+}
+
+// splitFuncName splits a function name qualified with a receiver type, like `Type.Method` or `(*Type).Method`,
+// into the receiver type name, whether the receiver is a pointer and the method name.
+// The receiver type name is empty for an unqualified name.
+func splitFuncName(funcName string) (string, bool, string) {
+	dot := strings.LastIndex(funcName, `.`)
+	if dot < 0 {
+		return ``, false, funcName
+	}
+
+	recvName, name := funcName[:dot], funcName[dot+1:]
+	if strings.HasPrefix(recvName, `(*`) && strings.HasSuffix(recvName, `)`) {
+		return recvName[2 : len(recvName)-1], true, name
+	}
+
+	return recvName, false, name
+}
+
+// hasRecv reports whether the function declaration is a method of the named receiver type,
+// with a pointer receiver if pointer is set
+func hasRecv(fn *ast.FuncDecl, recvName string, pointer bool) bool {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return false
+	}
+
+	typ := unparen(fn.Recv.List[0].Type)
+	star, isPointer := typ.(*ast.StarExpr)
+	if isPointer {
+		typ = unparen(star.X)
+	} else if pointer {
+		return false
+	}
+
+	// Strip the type parameters of a generic receiver, like T[K]
+	switch t := typ.(type) {
+	case *ast.IndexExpr:
+		typ = t.X
+	case *ast.IndexListExpr:
+		typ = t.X
+	}
+
+	ident, ok := typ.(*ast.Ident)
+	return ok && ident.Name == recvName
+}
+
+// varFuncLit returns the function registered for the package level variable of the package with the given name
+// initialized with a function literal, like `var F = func() {...}`, or nil if there is no such variable
+func (m *ModuleParser) varFuncLit(pkg *packages.Package, name string) *types.Func {
+	for obj := range m.varFuncLits {
+		if obj.Pkg() == pkg.Types && obj.Name() == name {
+			return obj
+		}
+	}
+
+	return nil
+}
+
+// collectVarFuncLits registers the function literals initializing the package level variables of the declaration
+// as the declarations of functions with the name of the variable, so they can be used as root
+func (m *ModuleParser) collectVarFuncLits(pkg *packages.Package, file *ast.File, gd *ast.GenDecl) {
+	for _, spec := range gd.Specs {
+		vs := spec.(*ast.ValueSpec)
+		for i, ident := range vs.Names {
+			if i >= len(vs.Values) {
+				continue
+			}
+
+			lit, ok := unparen(vs.Values[i]).(*ast.FuncLit)
+			if !ok {
+				continue
+			}
+			sig, ok := pkg.TypesInfo.TypeOf(lit).(*types.Signature)
+			if !ok {
+				continue
+			}
+
+			doc := vs.Doc
+			if doc == nil && !gd.Lparen.IsValid() {
+				doc = gd.Doc
+			}
+
+			// The declaration starts at the variable declaration, so it is extracted with the variable name
+			typ := *lit.Type
+			typ.Func = vs.Pos()
+			if !gd.Lparen.IsValid() {
+				typ.Func = gd.Pos()
+			}
+
+			obj := types.NewFunc(ident.Pos(), pkg.Types, ident.Name, sig)
+			m.varFuncLits[obj] = &ast.FuncDecl{Doc: doc, Name: ident, Type: &typ, Body: lit.Body}
+			m.funcToFileAndPkg[obj] = fileAndPkg{file: file, pkg: pkg, exported: ident.IsExported()}
+		}
+	}
+}
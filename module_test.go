@@ -0,0 +1,470 @@
+package scparser
+
+import (
+	"context"
+	"errors"
+	goparser "go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNestedSubpackage(t *testing.T) {
+	output, err := ParseE(filepath.Join(testModDir, `sub`, `deep`), `DeepRoot`, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertContains(t, output, `func DeepRoot() int {`, "alpha\n```go\n// Sum adds the numbers.")
+}
+
+func TestPackageLevelFuncLitRoot(t *testing.T) {
+	m := testModule(t)
+	funcs, lits := len(m.funcToFileAndPkg), len(m.varFuncLits)
+	output := parseTest(t, `LitRoot`, Options{})
+	if len(m.funcToFileAndPkg) != funcs || len(m.varFuncLits) != lits {
+		t.Error(`looking up the root changed the functions of the module`)
+	}
+
+	assertContains(t, output, "// LitRoot is a function literal assigned to a package level variable.\nvar LitRoot = func() {\n\tlitHelper()\n}\n", `func litHelper() {}`)
+}
+
+func TestEveryRootIsIncluded(t *testing.T) {
+	m := testModule(t)
+	roots := []string{`DescribeRoot`, `litHelper`}
+
+	for _, opts := range []Options{{MinFunctionLines: 3}, {ExportedOnly: true}, {ExcludePrefixes: []string{`example.com/mod`}}} {
+		output := m.ParseMany(roots, opts)
+		assertContains(t, output, `func DescribeRoot() string {`, `func litHelper() {}`)
+	}
+
+	var funcs []*types.Func
+	for _, name := range roots {
+		obj, err := m.lookupFunction(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		funcs = append(funcs, obj)
+	}
+	output, err := m.SourceOfMany(funcs, Options{MinFunctionLines: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, output, `func DescribeRoot() string {`, `func litHelper() {}`)
+}
+
+func TestOmittedCalleesNoteFollowsFunction(t *testing.T) {
+	src := parseTest(t, `Server.Handle`, Options{MaxCalleesPerFunction: 1, IncludeReceiverTypes: true})
+
+	assertContains(t, src, "\tencode()\n}\n// (1 more callees omitted)\n")
+	assertContains(t, src, `type Server struct{ name string }`)
+	assertNotContains(t, src, "struct{ name string }\n// (1 more callees omitted)")
+}
+
+func TestMissingFunctionErrors(t *testing.T) {
+	m := testModule(t)
+
+	if _, err := m.ParseWithOptionsE(`NoSuchRoot`, Options{}); !errors.Is(err, ErrFunctionNotFound) {
+		t.Errorf("ParseWithOptionsE returned %v, want ErrFunctionNotFound", err)
+	}
+	if _, _, err := m.ParseWithOptionsHashE(`NoSuchRoot`, Options{}); !errors.Is(err, ErrFunctionNotFound) {
+		t.Errorf("ParseWithOptionsHashE returned %v, want ErrFunctionNotFound", err)
+	}
+	if err := m.ParseToDir(t.TempDir(), `NoSuchRoot`, Options{}); !errors.Is(err, ErrFunctionNotFound) {
+		t.Errorf("ParseToDir returned %v, want ErrFunctionNotFound", err)
+	}
+}
+
+// writeFiles writes the files with the given contents, keyed by slash separated paths, into the directory
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// gitCommit commits all files of the repository in the directory, initializing it first if needed
+func gitCommit(t *testing.T, dir string) {
+	t.Helper()
+
+	for _, args := range [][]string{{`init`, `-q`}, {`add`, `-A`}, {`-c`, `user.name=test`, `-c`, `user.email=test@example.com`, `commit`, `-q`, `-m`, `commit`}} {
+		if out, err := exec.Command(`git`, append([]string{`-C`, dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+}
+
+func TestParseChangedCalleePackage(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		`go.mod`:     "module example.com/chg\n\ngo 1.21\n",
+		`root.go`:    "package chg\n\nimport \"example.com/chg/dep\"\n\n// Root calls Work.\nfunc Root() { dep.Work(1) }\n",
+		`dep/dep.go`: "package dep\n\n// Work works.\nfunc Work(n int) int { return n }\n",
+	})
+	gitCommit(t, dir)
+	writeFiles(t, dir, map[string]string{`dep/dep.go`: "package dep\n\n// Work works.\nfunc Work(n int) int { return n * 2 }\n"})
+	gitCommit(t, dir)
+
+	output, err := ParseChanged(dir, `Root`, `HEAD~1`, `HEAD`, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "dep\n```go\n// Work works.\nfunc Work(n int) int { return n * 2 }\n```"; output != want {
+		t.Errorf("output is %q, want %q", output, want)
+	}
+
+	var parseErr *ParseError
+	if _, err := ParseChanged(dir, `Root`, `HEAD~1`, `nosuchrev`, Options{}); !errors.As(err, &parseErr) {
+		t.Errorf("ParseChanged with an unknown revision returned %v, want a *ParseError", err)
+	}
+}
+
+func TestModuleCacheSourceKeepsModule(t *testing.T) {
+	dir := t.TempDir()
+	// The module of the leaf package is only required by the mid module, so it is not listed in go.mod
+	writeFiles(t, dir, map[string]string{
+		`go.mod`:       "module example.com/app\n\ngo 1.16\n\nrequire example.com/mid v0.0.0\n\nreplace example.com/mid => ./mid\n\nreplace example.com/leaf => ./leaf\n",
+		`app.go`:       "package app\n\nimport \"example.com/mid\"\n\n// Root calls a dependency.\nfunc Root() int { return mid.Run() }\n",
+		`mid/go.mod`:   "module example.com/mid\n\ngo 1.16\n\nrequire example.com/leaf v0.0.0\n",
+		`mid/mid.go`:   "package mid\n\nimport \"example.com/leaf\"\n\n// Run calls an indirect dependency.\nfunc Run() int { return leaf.Work() }\n",
+		`leaf/go.mod`:  "module example.com/leaf\n\ngo 1.16\n",
+		`leaf/leaf.go`: "package leaf\n\n// Work is loaded from the module cache.\nfunc Work() int { return 1 }\n",
+	})
+
+	m, err := LoadModule(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	funcs := len(m.funcToFileAndPkg)
+
+	output, err := m.ParseWithOptionsE(`Root`, Options{IncludeModuleCacheSource: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, output, `func Work() int { return 1 }`)
+
+	if len(m.funcToFileAndPkg) != funcs {
+		t.Error(`the functions loaded from the module cache were added to the module`)
+	}
+	output, err = m.ParseWithOptionsE(`Root`, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertNotContains(t, output, `func Work()`)
+}
+
+func TestIncludeTests(t *testing.T) {
+	output := parseTest(t, `DocRoot`, Options{IncludeTests: true})
+
+	assertContains(t, output, "// tests\n\nfunc TestDocRoot(t *testing.T) {", `func testHelper(t *testing.T) {}`)
+	if n := len(testModule(t).testPkgs); n == 0 {
+		t.Error(`no test packages loaded`)
+	}
+	for _, pkg := range testModule(t).testPkgs {
+		if !isGoModPkg([]string{`example.com/mod`}, strings.TrimSuffix(pkg.PkgPath, `_test`)) && !strings.HasSuffix(pkg.PkgPath, `.test`) {
+			t.Errorf("tests of %s loaded", pkg.PkgPath)
+		}
+	}
+}
+
+func TestParseToDirWritesGoFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := testModule(t).ParseToDir(dir, `SignatureRoot`, Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{`example.com_mod.go`, `example.com_mod_alpha.go`, `example.com_mod_beta.go`} {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := goparser.ParseFile(token.NewFileSet(), name, content, 0); err != nil {
+			t.Errorf("%s does not parse: %v", name, err)
+		}
+		if name == `example.com_mod.go` {
+			assertContains(t, string(content), "package mod\n", `"example.com/mod/alpha"`, `"example.com/mod/beta"`)
+		}
+	}
+
+	var parseErr *ParseError
+	if err := ParseToDir(dir, testModDir, `NoSuchRoot`, Options{}); !errors.As(err, &parseErr) || !errors.Is(err, ErrFunctionNotFound) {
+		t.Errorf("ParseToDir returned %v, want a *ParseError wrapping ErrFunctionNotFound", err)
+	}
+}
+
+func TestParseResultContentHash(t *testing.T) {
+	opts := Options{MaxDepth: 3}
+	r, err := testModule(t).ParseStructured(`SignatureRoot`, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if hash, want := r.ContentHash(), ContentHash(parseTest(t, `SignatureRoot`, opts)); hash != want {
+		t.Errorf("ContentHash is %s, want %s", hash, want)
+	}
+}
+
+func TestParseCompilableVets(t *testing.T) {
+	for _, root := range []string{`PackagesRoot`, `EmbedRoot`, `MethodValueRoot`, `DescribeRoot`, `AliasRoot`, `HintRoot`} {
+		t.Run(root, func(t *testing.T) {
+			files, err := testModule(t).ParseCompilable(root, Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			dir := t.TempDir()
+			written := map[string]string{`go.mod`: "module example.com/mod\n\ngo 1.21\n"}
+			for pkgPath, content := range files {
+				written[strings.TrimPrefix(strings.TrimPrefix(pkgPath, `example.com/mod`), `/`)+`/extracted.go`] = content
+			}
+			writeFiles(t, dir, written)
+
+			cmd := exec.Command(`go`, `vet`, `./...`)
+			cmd.Dir = dir
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Errorf("go vet: %v: %s\n%v", err, out, files)
+			}
+		})
+	}
+}
+
+func TestTestsOption(t *testing.T) {
+	m, err := newModuleParser(testModDir, Options{Tests: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := m.ParseWithOptionsE(`TestDocRoot`, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, output, "func TestDocRoot(t *testing.T) {", `func docHelper() {}`, `func testHelper(t *testing.T) {}`)
+
+	// The functions of the external test package are followed into the package they test, which keeps its name
+	m.rootPkgPath = `example.com/mod_test`
+	output, err = m.ParseWithOptionsE(`TestDocRootExternal`, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, output, "func TestDocRootExternal(t *testing.T) {", "mod\n```go\n// DocRoot calls a documented helper.")
+	assertNotContains(t, output, `.test`)
+
+	if _, err := testModule(t).ParseWithOptionsE(`TestDocRoot`, Options{}); !errors.Is(err, ErrFunctionNotFound) {
+		t.Errorf("expected ErrFunctionNotFound without Tests, got %v", err)
+	}
+}
+
+func TestReachablePackages(t *testing.T) {
+	paths, err := ReachablePackages(testModDir, `PackagesRoot`, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(paths, ` `); got != `example.com/mod example.com/mod/alpha example.com/mod/beta` {
+		t.Errorf("unexpected packages %s", got)
+	}
+
+	m := testModule(t)
+	for _, tc := range []struct {
+		opts Options
+		want string
+	}{
+		{Options{ExcludeRoot: true}, `example.com/mod/alpha example.com/mod/beta`},
+		{Options{MaxDepth: 2}, `example.com/mod example.com/mod/alpha`},
+		{Options{ExcludePrefixes: []string{`example.com/mod/beta`}}, `example.com/mod example.com/mod/alpha`},
+	} {
+		paths, err := m.ReachablePackages(`PackagesRoot`, tc.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(paths, ` `); got != tc.want {
+			t.Errorf("%+v: expected %s, got %s", tc.opts, tc.want, got)
+		}
+	}
+
+	if _, err := m.ReachablePackages(`NoSuchRoot`, Options{}); !errors.Is(err, ErrFunctionNotFound) {
+		t.Errorf("expected ErrFunctionNotFound, got %v", err)
+	}
+}
+
+func TestParseConstructors(t *testing.T) {
+	m := testModule(t)
+
+	output, err := m.ParseConstructors(`Widget`, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, output, `func NewWidget() (*Widget, error) {`, `func ZeroWidget() Widget {`, "func widgetName() string { return `widget` }")
+	assertNotContains(t, output, `func (w *Widget) Clone()`)
+	if strings.Index(output, `func NewWidget(`) > strings.Index(output, `func ZeroWidget(`) {
+		t.Errorf("expected the constructors in declaration order:\n%s", output)
+	}
+
+	if _, err := m.ParseConstructors(`example.com/mod.Repo`, Options{}); err == nil {
+		t.Error(`expected an error for a type without constructors`)
+	}
+	if _, err := m.ParseConstructors(`NoSuchType`, Options{}); err == nil {
+		t.Error(`expected an error for an unknown type`)
+	}
+}
+
+func TestSourceOfManyExactFunctions(t *testing.T) {
+	// The functions come from a separate load of the packages, so they are matched by name
+	other, err := newModuleParser(testModDir, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var funcs []*types.Func
+	for _, name := range []string{`StreamRoot`, `DocRoot`, `streamHelper`, `StreamRoot`} {
+		obj, err := other.lookupFunction(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		funcs = append(funcs, obj)
+	}
+	funcs = append(funcs, other.pkgByPath[`example.com/mod/alpha`].Types.Scope().Lookup(`Sum`).(*types.Func))
+
+	output, err := testModule(t).SourceOfMany(funcs, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(output, `func StreamRoot() int {`); n != 1 {
+		t.Errorf("expected StreamRoot once, got %d times:\n%s", n, output)
+	}
+	assertContains(t, output, "func DocRoot() {\n\tdocHelper()\n}\n\nfunc streamHelper() int { return 3 }\n```\n\nalpha\n```go\n// Sum adds the numbers.")
+	assertNotContains(t, output, `func docHelper()`)
+
+	missing := types.NewFunc(token.NoPos, types.NewPackage(`example.com/other`, `other`), `Missing`, types.NewSignatureType(nil, nil, nil, nil, nil, false))
+	if _, err := testModule(t).SourceOfMany([]*types.Func{missing}, Options{}); err == nil {
+		t.Error(`expected an error for a function outside the go.mod packages`)
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	output, err := ParseFile(filepath.Join(testModDir, `wrapper.go`), `WrapperRoot`, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The functions of the other files of the package are followed
+	assertContains(t, output, `func WrapperRoot(w *wrapped) int {`, `func (w *wrapped) Do() int { return w.inner.Do() }`, `func realHelper() int { return 1 }`)
+
+	// Only the package of the file is loaded, so the functions of other packages are not followed
+	output, err = ParseFile(filepath.Join(testModDir, `packages.go`), `PackagesRoot`, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, output, `func PackagesRoot() {`)
+	assertNotContains(t, output, `func Start()`)
+
+	// The root is looked up in the file only
+	var perr *ParseError
+	if _, err := ParseFile(filepath.Join(testModDir, `doc.go`), `PackagesRoot`, 1); !errors.As(err, &perr) || !errors.Is(err, ErrFunctionNotFound) {
+		t.Errorf("expected a *ParseError wrapping ErrFunctionNotFound, got %v", err)
+	}
+}
+
+// cancelAfter is a context which is canceled once its Err method is called more than n times
+type cancelAfter struct {
+	context.Context
+	n int
+}
+
+func (c *cancelAfter) Err() error {
+	if c.n == 0 {
+		return context.Canceled
+	}
+	c.n--
+
+	return nil
+}
+
+func TestParseContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var perr *ParseError
+	if _, err := ParseContext(ctx, testModDir, `DocRoot`, Options{}); !errors.As(err, &perr) || !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a *ParseError wrapping context.Canceled, got %v", err)
+	}
+
+	// The functions processed before the cancellation are returned with the error
+	output, err := testModule(t).ParseContext(&cancelAfter{Context: context.Background(), n: 2}, `ShortcutRoot`, Options{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	assertContains(t, output, `func ShortcutRoot() {`, `func longA() { longB() }`)
+	assertNotContains(t, output, `func longB()`, `func shortTarget()`)
+}
+
+func TestEdges(t *testing.T) {
+	m := testModule(t)
+	for _, tc := range []struct {
+		root string
+		opts Options
+		want string
+	}{
+		{`ShortcutRoot`, Options{}, `ShortcutRoot>longA longA>longB longB>shortTarget shortTarget>shortBelow ShortcutRoot>shortTarget shortBelow>shortBottom`},
+		// The calls below the maximum depth are not followed
+		{`ShortcutRoot`, Options{MaxDepth: 3}, `ShortcutRoot>longA longA>longB ShortcutRoot>shortTarget shortTarget>shortBelow`},
+		{`PackagesRoot`, Options{}, `PackagesRoot>alpha.Start alpha.Start>beta.Mid`},
+		// The calls of excluded functions are not included
+		{`PackagesRoot`, Options{ExcludePrefixes: []string{`example.com/mod/beta`}}, `PackagesRoot>alpha.Start`},
+		// The calls of the functions left out by MaxBytes are not included
+		{`BudgetRoot`, Options{MaxBytes: 250}, `BudgetRoot>budgetDeep BudgetRoot>budgetNear`},
+	} {
+		r, err := m.ParseStructured(tc.root, tc.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var edges []string
+		for _, edge := range r.Edges() {
+			edges = append(edges, strings.TrimPrefix(strings.TrimPrefix(edge[0], `example.com/mod.`), `example.com/mod/`)+`>`+
+				strings.TrimPrefix(strings.TrimPrefix(edge[1], `example.com/mod.`), `example.com/mod/`))
+		}
+		if got := strings.Join(edges, ` `); got != tc.want {
+			t.Errorf("%s %+v: expected edges %s, got %s", tc.root, tc.opts, tc.want, got)
+		}
+	}
+}
+
+func TestAmbiguousMethodName(t *testing.T) {
+	m := testModule(t)
+
+	_, err := m.ParseWithOptionsE(`Load`, Options{})
+	if !errors.Is(err, ErrAmbiguousFunction) {
+		t.Fatalf("expected ErrAmbiguousFunction, got %v", err)
+	}
+	for _, candidate := range []string{`(*example.com/mod.Memory).Load`, `(*example.com/mod.Disk).Load`, `(*example.com/mod.Widget).Load`} {
+		if !strings.Contains(err.Error(), candidate) {
+			t.Errorf("error %q does not list %s", err, candidate)
+		}
+	}
+	if _, err := m.Exists(`Load`); !errors.Is(err, ErrAmbiguousFunction) {
+		t.Errorf("expected ErrAmbiguousFunction from Exists, got %v", err)
+	}
+
+	var perr *ParseError
+	if _, err := ParseE(testModDir, `Load`, false, false); !errors.As(err, &perr) || !errors.Is(err, ErrAmbiguousFunction) {
+		t.Errorf("expected a *ParseError wrapping ErrAmbiguousFunction, got %v", err)
+	}
+
+	// Qualifying the method with its receiver type resolves the ambiguity
+	output, err := m.ParseWithOptionsE(`Disk.Load`, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, output, `func (d *Disk) Load() string { return d.path }`)
+	assertNotContains(t, output, `func (m *Memory) Load()`)
+}
@@ -0,0 +1,228 @@
+package scparser
+
+import "golang.org/x/tools/go/packages"
+
+// Options configures how the source code of a function and its underlying functions is retrieved.
+type Options struct {
+	// ExcludeRoot excludes the root package from the output
+	ExcludeRoot bool
+
+	// CodeOnly formats the output as Go code instead of markdown
+	CodeOnly bool
+
+	// MaxDepth is the maximum depth of functions to process, where the root function is at depth 1.
+	// 0 uses the default depth of 5. If the root is excluded, one more level is processed.
+	MaxDepth int
+
+	// PackageDepths caps the depth per package, by import path prefix. Functions of a matching package
+	// are processed with at most the given depth below them, counting the function itself as 1. The
+	// longest matching prefix is used, packages without a match fall back to MaxDepth.
+	PackageDepths map[string]int
+
+	// IncludePrefixes limits the functions that are followed to the packages matching one of the given import
+	// paths or their subpackages, e.g. `github.com/x/y` or `github.com/x/y/...`. The calls of other packages
+	// are treated as leaves. Empty follows the functions of all go mod packages.
+	IncludePrefixes []string
+
+	// SignaturesOutsidePrefixes includes the doc comments and signatures of the functions outside IncludePrefixes
+	// called by the followed functions, without following them further.
+	SignaturesOutsidePrefixes bool
+
+	// ExcludePrefixes leaves out the functions of the packages matching one of the given import paths or their
+	// subpackages, e.g. generated code or logging wrappers, together with everything only reached through them.
+	// The root function is always included.
+	ExcludePrefixes []string
+
+	// MaxCalleesPerFunction limits the number of distinct new underlying functions followed from each
+	// function, 0 means no limit. The callees are followed in source order, the rest is summarized as
+	// a comment with the number of omitted callees below the function.
+	MaxCalleesPerFunction int
+
+	// MaxBytes limits the total size of the extracted source code, 0 means no limit.
+	// When set, functions are processed breadth-first, so the functions nearest to the root
+	// are kept and the output is marked as truncated once the limit is reached. Functions at the same
+	// depth are processed in the order they are called, except that exported functions go before unexported ones.
+	// No further functions are processed after the first one that does not fit, so the result does not depend on
+	// the size of the functions left out. The root function is always included. The limit applies to the source
+	// code of the declarations, the package headers, fences and the sections following the functions, like the
+	// tests and the trailing `Truncated` note, are not counted.
+	MaxBytes int
+
+	// Concurrency is the number of goroutines looking up the declarations of the functions called by each function
+	// and extracting their source code in parallel, 0 or 1 for none. Only this preparation is parallel: the call
+	// graph is still walked by a single goroutine, following the functions one after the other in the same order,
+	// so the output is the same as without it and the speedup is limited to the time spent extracting source code.
+	Concurrency int
+
+	// SignatureOnlyBelowDepth includes only the doc comments and signatures of the functions reached below the
+	// given depth, where the root function is at depth 1, so the bodies are kept near the root and the deeper
+	// functions still document the API they provide. 0 includes all bodies.
+	SignatureOnlyBelowDepth int
+
+	// DocsOnly includes only the doc comments and signatures of the functions, omitting their bodies.
+	// The underlying functions are still followed as usual.
+	DocsOnly bool
+
+	// Flatten omits the package headers and emits the functions of all packages as one block,
+	// in the order the packages and their functions were processed.
+	Flatten bool
+
+	// FenceFunctions emits each function in a fenced block of its own, with its fully qualified name in the
+	// info string, e.g. ```go title=github.com/x/y.Func. The receiver groups of GroupByReceiver are not marked.
+	// It has no effect with CodeOnly.
+	FenceFunctions bool
+
+	// FenceLanguage is the language in the info string of the opening fences of the markdown code blocks,
+	// e.g. ```go, which markdown renderers use for syntax highlighting. Empty uses go. It has no effect with CodeOnly.
+	FenceLanguage string
+
+	// IncludeImports starts the block of each package with the import declaration of the imports used by its
+	// functions, with their alias if they are imported under another name, so qualified identifiers like
+	// `json.Marshal` can be resolved. Dot and blank imports are not included.
+	IncludeImports bool
+
+	// GroupByReceiver groups the functions of each package by receiver type, under a `// type T` header.
+	// Functions without a receiver are grouped first, under a `// functions` header.
+	GroupByReceiver bool
+
+	// MaxFilesPerPackage limits the number of files of which the functions are included per package, 0 means
+	// no limit. The files are included in the order they were first processed, the rest is summarized as
+	// a comment with the number of omitted files.
+	MaxFilesPerPackage int
+
+	// MinFunctionLines omits functions with fewer body lines from the output, while still
+	// following their underlying functions. The root function is always included.
+	MinFunctionLines int
+
+	// ExportedOnly leaves the unexported functions and methods out of the output, without following their
+	// underlying functions, e.g. for documenting the public API. The root function is always included.
+	ExportedOnly bool
+
+	// AnnotateDepth marks each function with a `// depth=N` comment above it, where N is the shortest depth at which
+	// it is reached from a root function, the root functions being at depth 1.
+	AnnotateDepth bool
+
+	// IncludeLeadingComments includes the comments directly above a function which are separated from it by a blank
+	// line, so they are not part of its doc comments, like license or explanatory comments, with the blank line.
+	IncludeLeadingComments bool
+
+	// MarkPanicRecover marks the functions calling the panic or recover builtin with a `// contains panic/recover`
+	// comment above them, highlighting functions with non-obvious control flow.
+	MarkPanicRecover bool
+
+	// IncludeTests appends the test functions of the main module packages referencing one of the extracted functions,
+	// together with the helpers in the test files they call, in a separate `// tests` section. The tests are
+	// loaded the first time they are needed.
+	IncludeTests bool
+
+	// DedupBySource emits functions with an identical gofmt normalized signature and body, common in generated
+	// adapters, only once. The first processed function is kept with an `// also:` comment listing the others.
+	// Root functions are always emitted.
+	DedupBySource bool
+
+	// IncludeReferencedDecls includes the declarations of the package level types, constants and variables of the
+	// go mod packages which are used by the included functions, e.g. the struct types of their parameters and
+	// the error variables they return. They are included once, in the block of their package after the first
+	// function using them, grouped constants together with their group.
+	IncludeReferencedDecls bool
+
+	// IncludeReceiverTypes includes the declaration of the receiver type of each included method, once, after the
+	// first of its methods, so the fields of the struct are shown along with its methods.
+	IncludeReceiverTypes bool
+
+	// InterfaceAssertions includes the interface assertions, such as `var _ io.Writer = (*T)(nil)`,
+	// of the receiver types of the methods and of the types in the output.
+	InterfaceAssertions bool
+
+	// IncludeOnlyFunctions limits the functions of which the body is included to the given fully
+	// qualified names, e.g. `github.com/x/y.Func` or `(*github.com/x/y.Type).Method`. The other
+	// reached functions are still followed, but only their doc comments and signatures are included.
+	IncludeOnlyFunctions []string
+
+	// SkipGeneratedWrappers sees through functions in generated files of which the body is a single
+	// delegating call. The wrapper is replaced by a comment marking its elision, and the function it
+	// delegates to is processed in its place without using up a level of depth.
+	SkipGeneratedWrappers bool
+
+	// FollowVarInitializers follows the functions and methods referenced as values in the composite literals
+	// initializing package level variables used by the processed functions, e.g. the wiring of a dependency
+	// injection container like `var deps = Deps{Save: repo.Save}`.
+	FollowVarInitializers bool
+
+	// FollowFuncFields follows the calls of struct fields of function type, like `s.handler()`, to the functions
+	// and methods assigned to the field anywhere in the go mod packages. This is a heuristic which only handles
+	// straightforward assignments of named functions and method values, like `&Server{handler: s.serve}` in a
+	// constructor or `s.handler = serve`, regardless of the value the field holds at the call.
+	FollowFuncFields bool
+
+	// StubExternal lists the signatures of called functions which are declared outside the loaded
+	// go.mod packages (e.g. the standard library) as comments at the end of the output.
+	StubExternal bool
+
+	// ExternalVersions annotates each external function listed by StubExternal with the path and
+	// version of the go.mod module providing it.
+	ExternalVersions bool
+
+	// IncludeModuleCacheSource loads the source code of called functions of dependencies which are not listed
+	// in go.mod, such as indirect dependencies of older modules, so they are followed like the go.mod packages
+	// up to the same depth. The standard library is still considered external.
+	IncludeModuleCacheSource bool
+
+	// ExternalCalls lists the distinct called functions which are declared outside the loaded go.mod packages
+	// by import path and name, sorted, in an `// External calls:` section at the end of the output.
+	ExternalCalls bool
+
+	// Verify checks that the extracted source code of each function parses as Go. The functions that fail
+	// are reported as a *VerifyError only by ModuleParser.ParseWithOptionsE and ParseWithOptionsHashE, the
+	// other functions ignore it.
+	Verify bool
+
+	// ResolveInterfaces follows calls of abstract methods to the implementations in the go mod packages: the method
+	// of every go mod type implementing the interface is followed as an underlying function of the caller, within
+	// the same depth. This can pull in a lot of code for widely implemented interfaces. Without it, a method called
+	// on a type parameter includes the declaration of its constraint instead.
+	ResolveInterfaces bool
+
+	// InterfaceOrder names a go mod interface as `pkg.Interface`, where pkg is the package name or import path.
+	// The methods of the types implementing it are emitted in the order the methods are declared in the
+	// interface, with the methods of embedded interfaces in their place, instead of the order they were processed.
+	InterfaceOrder string
+
+	// IncludePreamble starts the output with a comment block recording the scparser version, the module path,
+	// the root functions, the depth and the options set, so a saved extraction documents how it was produced.
+	IncludePreamble bool
+
+	// Tests loads the go mod packages including their test files, so functions declared in _test.go files can be
+	// used as root and are followed like the other functions. The external _test packages are loaded as well. Unlike
+	// IncludeTests, the tests are not collected in a separate section. It applies to the package level functions.
+	Tests bool
+
+	// BuildTags are the build tags to load the packages with, so files constrained by `//go:build` lines for
+	// these tags are included. It applies to the package level functions.
+	BuildTags []string
+
+	// GOOS and GOARCH are the target operating system and architecture to load the packages for, to extract the
+	// platform specific implementation of a function. Empty uses the current platform. They apply to the package
+	// level functions.
+	GOOS   string
+	GOARCH string
+
+	// Overlay maps absolute file paths to the contents to load them with instead of the contents on disk, or adds
+	// them as files if they do not exist, to parse generated or edited code that is not written. It applies to
+	// the package level functions.
+	Overlay map[string][]byte
+
+	// Vendor runs `go mod vendor` in the module directory before loading the packages if the vendor directory
+	// is missing or older than go.mod or go.sum, for builds that resolve the dependencies from the vendor directory
+	// only. By default the packages are loaded from the module cache without writing to the module directory.
+	// It applies to the package level functions, which load the module themselves.
+	Vendor bool
+
+	// PackageHeader formats the header preceding the block of each package but the first, e.g. to include the import
+	// path as `### package y (github.com/x/y)`. By default it is the package name, as a comment with CodeOnly.
+	PackageHeader func(pkg *packages.Package) string
+
+	// Order orders the functions within each package before they are rendered, reporting whether a goes
+	// before b. Nil keeps the order in which they were processed. See OrderByName for presets.
+	Order func(a, b Function) bool
+}
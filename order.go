@@ -0,0 +1,100 @@
+package scparser
+
+import (
+	"go/token"
+	"sort"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Function is an extracted declaration, as passed to the Order comparator of the options and returned in a ParseResult
+type Function struct {
+	// Name is the fully qualified name, e.g. `github.com/x/y.Func` or `(*github.com/x/y.Type).Method`
+	Name string `json:"name"`
+
+	// PkgPath is the import path of the package declaring the function
+	PkgPath string `json:"pkgPath"`
+
+	// File and Line are the position of the declaration, EndLine is the line it ends at
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	EndLine int    `json:"endLine"`
+
+	// Start and End are the positions the declaration starts and ends at, with their columns and byte offsets,
+	// to map the output back to the source. The doc comments are not included.
+	Start token.Position `json:"start"`
+	End   token.Position `json:"end"`
+
+	// Depth is the level of the function in the call tree, where the root function is at 1.
+	// It is 0 for the type, constant and variable declarations and interface assertions included alongside the functions.
+	Depth int `json:"depth"`
+
+	// Source is the extracted source code
+	Source string `json:"source"`
+
+	// Index is the position of the declaration in the order of processing
+	Index int `json:"-"`
+}
+
+// OrderByName orders the functions by their fully qualified name
+func OrderByName(a, b Function) bool {
+	return a.Name < b.Name
+}
+
+// OrderByFile orders the functions by their position in the source files
+func OrderByFile(a, b Function) bool {
+	if a.File != b.File {
+		return a.File < b.File
+	}
+
+	return a.Line < b.Line
+}
+
+// OrderByDepth orders the functions by their depth in the call tree, nearest to the root first
+func OrderByDepth(a, b Function) bool {
+	return a.Depth < b.Depth
+}
+
+// OrderBySize orders the functions by the size of their source code, smallest first
+func OrderBySize(a, b Function) bool {
+	return len(a.Source) < len(b.Source)
+}
+
+// exportFunction returns the function of the package at the index in the order of processing as a Function
+func exportFunction(pkg *packages.Package, fn function, index int) Function {
+	return Function{
+		Name:    fn.name,
+		PkgPath: pkg.PkgPath,
+		File:    fn.start.Filename,
+		Line:    fn.start.Line,
+		EndLine: fn.end.Line,
+		Start:   fn.start,
+		End:     fn.end,
+		Depth:   fn.level,
+		Source:  fn.src,
+		Index:   index,
+	}
+}
+
+// orderFunctions orders the functions of the package with the Order comparator.
+// Functions the comparator considers equal keep the order in which they were processed.
+func (p *parser) orderFunctions(pkg *packages.Package, functions []function) []function {
+	if p.opts.Order == nil {
+		return functions
+	}
+
+	exported := make([]Function, len(functions))
+	for i, fn := range functions {
+		exported[i] = exportFunction(pkg, fn, i)
+	}
+	sort.SliceStable(exported, func(a, b int) bool {
+		return p.opts.Order(exported[a], exported[b])
+	})
+
+	ordered := make([]function, len(functions))
+	for i, fn := range exported {
+		ordered[i] = functions[fn.Index]
+	}
+
+	return ordered
+}
@@ -0,0 +1,39 @@
+package scparser
+
+import (
+	"go/ast"
+	"go/types"
+)
+
+// panicRecoverMarker is the comment marking functions calling the panic or recover builtin
+const panicRecoverMarker = "// contains panic/recover\n"
+
+// callsPanicOrRecover reports whether the body of the function, including its function literals,
+// calls the panic or recover builtin
+func callsPanicOrRecover(info *types.Info, fn *ast.FuncDecl) bool {
+	if fn.Body == nil {
+		return false
+	}
+
+	var found bool
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		if found {
+			return false
+		}
+
+		ce, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+
+		if ident, ok := unparen(ce.Fun).(*ast.Ident); ok {
+			if builtin, ok := info.ObjectOf(ident).(*types.Builtin); ok && (builtin.Name() == `panic` || builtin.Name() == `recover`) {
+				found = true
+			}
+		}
+
+		return !found
+	})
+
+	return found
+}
@@ -0,0 +1,104 @@
+package scparser
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sync"
+)
+
+// preparedFunction is the declaration of a queued function and its source code, prepared ahead of processing it
+type preparedFunction struct {
+	decl *ast.FuncDecl
+	src  string
+	err  error
+}
+
+// extracted returns the prepared source code, to be used in place of extractFunction
+func (f preparedFunction) extracted(*token.FileSet, *ast.FuncDecl) (string, error) {
+	return f.src, f.err
+}
+
+// prepareCallees looks up the declarations of the functions called in the body which are not prepared yet and
+// extracts their source code, with up to Concurrency goroutines. Only the syntax trees and type information, which
+// are not modified after loading, and the file contents are accessed from the goroutines. The callees are then
+// followed in the same order as without Concurrency, and the processed functions and the output are only updated
+// by that sequential traversal, so the output is the same.
+func (p *parser) prepareCallees(info *types.Info, body *ast.BlockStmt) {
+	var pending []*types.Func
+	ast.Inspect(body, func(n ast.Node) bool {
+		ident, ok := n.(*ast.Ident)
+		if !ok {
+			return true
+		}
+
+		funcObj, ok := info.Uses[ident].(*types.Func)
+		if !ok || funcObj.Pkg() == nil {
+			return true
+		}
+		funcObj = funcObj.Origin()
+		if p.tests {
+			funcObj = p.testVariantFunc(funcObj)
+		}
+
+		if _, ok := p.prepared[funcObj]; ok || p.seen[funcObj] {
+			return true
+		}
+		if _, ok := p.varFuncLits[funcObj]; ok {
+			return true
+		}
+		if _, ok := p.funcToFileAndPkg[funcObj]; ok {
+			p.prepared[funcObj] = preparedFunction{}
+			pending = append(pending, funcObj)
+		}
+
+		return true
+	})
+	if len(pending) < 2 {
+		for _, funcObj := range pending {
+			delete(p.prepared, funcObj)
+		}
+		return
+	}
+
+	results := make([]preparedFunction, len(pending))
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < p.opts.Concurrency && w < len(pending); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				results[i] = p.prepareFunction(pending[i])
+			}
+		}()
+	}
+	for i := range pending {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	for i, funcObj := range pending {
+		p.prepared[funcObj] = results[i]
+	}
+}
+
+// prepareFunction looks up the declaration of the function and extracts its source code
+func (p *parser) prepareFunction(funcObj *types.Func) (prep preparedFunction) {
+	f := p.funcToFileAndPkg[funcObj]
+	prep.decl = declOf(f, funcObj)
+	if prep.decl == nil {
+		return prep
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			prep.src, prep.err = "", fmt.Errorf("%v", r)
+		}
+	}()
+	prep.src, prep.err = p.extractFunction(f.pkg.Fset, prep.decl)
+
+	return prep
+}
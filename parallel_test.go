@@ -0,0 +1,25 @@
+package scparser
+
+import "testing"
+
+func TestConcurrencyKeepsOutput(t *testing.T) {
+	m := testModule(t)
+
+	for _, root := range []string{`ChainRoot`, `Deep`, `PackagesRoot`, `ClosureRoot`, `MethodValueRoot`} {
+		for _, opts := range []Options{{}, {MaxDepth: 3}, {MaxDepth: 4, ResolveInterfaces: true}, {MaxBytes: 60}} {
+			want, err := m.ParseWithOptionsE(root, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			opts.Concurrency = 4
+			got, err := m.ParseWithOptionsE(root, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != want {
+				t.Errorf("%s with %+v differs with Concurrency:\n%s\nwant:\n%s", root, opts, got, want)
+			}
+		}
+	}
+}
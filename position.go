@@ -0,0 +1,108 @@
+package scparser
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+)
+
+// ParseAtOffset retrieves the source code of the function declaration enclosing the given byte offset in the file,
+// and its underlying functions within the Go module packages of the module containing the file.
+func ParseAtOffset(filename string, offset int, opts Options) (string, error) {
+	filename, err := filepath.Abs(filename)
+	if err != nil {
+		return "", err
+	}
+
+	dir, err := moduleDir(filepath.Dir(filename))
+	if err != nil {
+		return "", err
+	}
+
+	m, err := newModuleParser(dir, opts)
+	if err != nil {
+		return "", err
+	}
+
+	return m.ParseAtOffset(filename, offset, opts)
+}
+
+// ParseAtOffset retrieves the source code of the function declaration enclosing the given byte offset in the file,
+// and its underlying functions. The file must be one of the loaded go.mod package files.
+func (m *ModuleParser) ParseAtOffset(filename string, offset int, opts Options) (string, error) {
+	filename, err := filepath.Abs(filename)
+	if err != nil {
+		return "", err
+	}
+
+	funcObj, err := m.funcAtOffset(filename, offset)
+	if err != nil {
+		return "", err
+	}
+
+	p := newParser(m, opts)
+	p.process(funcObj, rootDepth(opts))
+
+	return p.toString(opts.ExcludeRoot, opts.CodeOnly), nil
+}
+
+// funcAtOffset returns the function of the declaration enclosing the byte offset in the file
+func (m *ModuleParser) funcAtOffset(filename string, offset int) (*types.Func, error) {
+	for _, pkg := range m.pkgs {
+		for _, file := range pkg.Syntax {
+			tokFile := pkg.Fset.File(file.Pos())
+			if tokFile == nil || filepath.Clean(tokFile.Name()) != filename {
+				continue
+			}
+
+			if offset < 0 || offset > tokFile.Size() {
+				return nil, fmt.Errorf("offset %d out of range in %s", offset, filename)
+			}
+			pos := tokFile.Pos(offset)
+
+			for _, decl := range file.Decls {
+				fn, ok := decl.(*ast.FuncDecl)
+				if !ok || fn.Name == nil || !enclosesPos(fn, pos) {
+					continue
+				}
+
+				if obj, ok := pkg.TypesInfo.ObjectOf(fn.Name).(*types.Func); ok {
+					return obj, nil
+				}
+			}
+
+			return nil, fmt.Errorf("no function declaration at offset %d in %s", offset, filename)
+		}
+	}
+
+	return nil, fmt.Errorf("file %s not found in the go.mod packages", filename)
+}
+
+// enclosesPos reports whether the position lies within the function declaration, including its doc comments
+func enclosesPos(fn *ast.FuncDecl, pos token.Pos) bool {
+	start := fn.Pos()
+	if fn.Doc != nil {
+		start = fn.Doc.Pos()
+	}
+
+	return start <= pos && pos < fn.End()
+}
+
+// moduleDir returns the nearest directory containing a go.mod file, starting at the given directory
+func moduleDir(dir string) (string, error) {
+	start := dir
+	for {
+		if _, err := os.Stat(filepath.Join(dir, `go.mod`)); err == nil {
+			return dir, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("%w: in %s or any of its parent directories", ErrGoModNotFound, start)
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,85 @@
+package scparser
+
+import (
+	"fmt"
+	"reflect"
+	"runtime/debug"
+	"sort"
+	"strings"
+)
+
+// modulePath is the module path of scparser, used to look up its version in the build info
+const modulePath = `github.com/elwint/scparser`
+
+// preamble returns the comment block recording how the output was produced: the scparser version,
+// the module path, the root functions, the depth and the options that are set
+func (p *parser) preamble() string {
+	depth := p.opts.MaxDepth
+	if depth == 0 {
+		depth = defaultDepth
+	}
+
+	var sb strings.Builder
+	sb.WriteString("// Extracted by scparser " + version() + "\n")
+	sb.WriteString("// Module: " + p.goModPaths[0] + "\n")
+	sb.WriteString("// Root: " + strings.Join(p.roots, `, `) + "\n")
+	sb.WriteString(fmt.Sprintf("// Depth: %d\n", depth))
+	sb.WriteString("// Options: " + formatOptions(p.opts) + "\n")
+
+	return sb.String()
+}
+
+// version returns the version of scparser the program is built with, `(devel)` if it is unknown
+func version() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return `(devel)`
+	}
+
+	if info.Main.Path == modulePath {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			if dep.Replace != nil {
+				return dep.Replace.Version
+			}
+			return dep.Version
+		}
+	}
+
+	return `(devel)`
+}
+
+// formatOptions formats the options that are set as space separated Name=value pairs, in declaration order.
+// Functions are formatted as `set`, so the output does not depend on their address, and the Overlay by its file names.
+func formatOptions(opts Options) string {
+	var fields []string
+	v := reflect.ValueOf(opts)
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if field.IsZero() {
+			continue
+		}
+
+		value := fmt.Sprint(field.Interface())
+		if field.Kind() == reflect.Func {
+			value = `set`
+		}
+		if overlay, ok := field.Interface().(map[string][]byte); ok {
+			names := make([]string, 0, len(overlay))
+			for name := range overlay {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			value = fmt.Sprint(names)
+		}
+		fields = append(fields, v.Type().Field(i).Name+`=`+value)
+	}
+
+	if len(fields) == 0 {
+		return `none`
+	}
+
+	return strings.Join(fields, ` `)
+}
@@ -0,0 +1,157 @@
+package scparser
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ParseResult is the structured form of the output of Parse, for post-processing the extracted functions
+type ParseResult struct {
+	// Packages are the packages of the extracted functions, in the order they appear in the output
+	Packages []Package
+
+	p     *parser
+	stats Stats
+}
+
+// Stats is the size of the extracted source code of a ParseResult
+type Stats struct {
+	// Functions and Packages are the numbers of extracted declarations and of the packages declaring them
+	Functions int
+	Packages  int
+
+	// Lines and Bytes are the total size of the source code of the declarations, without the package headers
+	// and fences
+	Lines int
+	Bytes int
+}
+
+// Package is a package of the extracted functions
+type Package struct {
+	// Name and PkgPath are the package name and import path
+	Name    string `json:"name"`
+	PkgPath string `json:"pkgPath"`
+
+	// Functions are the extracted declarations of the package, in the order they appear in the output
+	Functions []Function `json:"functions"`
+}
+
+// String formats the result the same way as Parse with the options the result was parsed with
+func (r *ParseResult) String() string {
+	return r.p.toString(r.p.opts.ExcludeRoot, r.p.opts.CodeOnly)
+}
+
+// ContentHash returns the ContentHash of the result formatted by String, the same digest as of the output of Parse
+// with the options the result was parsed with, without parsing again
+func (r *ParseResult) ContentHash() string {
+	return ContentHash(r.String())
+}
+
+// Stats returns the numbers of extracted declarations and packages and the size of their source code, to check
+// the result against a budget
+func (r *ParseResult) Stats() Stats {
+	return r.stats
+}
+
+// SkippedExternal returns the qualified names of the called functions which were not followed because they are
+// declared outside the loaded go.mod packages, e.g. `strings.TrimSpace` or `(*net/http.Client).Do`, in the
+// order they were first called
+func (r *ParseResult) SkippedExternal() []string {
+	names := make([]string, 0, len(r.p.external))
+	for _, fn := range r.p.external {
+		names = append(names, fn.FullName())
+	}
+
+	return names
+}
+
+// Edges returns the calls between the processed functions as pairs of the qualified names of the caller and the
+// callee, e.g. `github.com/x/y.Func` and `(*github.com/x/y.Type).Method`, in the order they were first followed.
+// Calls of functions left out of the output, e.g. by MaxBytes or ExcludePrefixes, are not included.
+func (r *ParseResult) Edges() [][2]string {
+	processed := make(map[string]bool)
+	for obj := range r.p.seen {
+		processed[obj.FullName()] = true
+	}
+
+	edges := make([][2]string, 0, len(r.p.calls))
+	for _, call := range r.p.calls {
+		if processed[call[1]] {
+			edges = append(edges, call)
+		}
+	}
+
+	return edges
+}
+
+// ParseStructured is like ParseWithDepth, but returns the extracted functions per package as a ParseResult.
+func ParseStructured(funcPkgPath, funcName string, depth int) (*ParseResult, error) {
+	if depth < 0 {
+		return nil, fmt.Errorf("invalid depth %d: must not be negative", depth)
+	}
+
+	m, err := newModuleParser(funcPkgPath, Options{})
+	if err != nil {
+		return nil, &ParseError{PkgPath: funcPkgPath, FuncName: funcName, Err: err}
+	}
+
+	r, err := m.ParseStructured(funcName, Options{MaxDepth: depth + 1})
+	if err != nil {
+		return nil, &ParseError{PkgPath: funcPkgPath, FuncName: funcName, Err: err}
+	}
+
+	return r, nil
+}
+
+// ParseStructured retrieves the specified function and its underlying functions as a ParseResult.
+func (m *ModuleParser) ParseStructured(funcName string, opts Options) (*ParseResult, error) {
+	funcObj, err := m.lookupFunction(funcName)
+	if err != nil {
+		return nil, err
+	}
+
+	p := newParser(m, opts)
+	p.process(funcObj, rootDepth(opts))
+
+	r := &ParseResult{p: p}
+	for k, pkg := range p.pkgOrder {
+		if k == 0 && opts.ExcludeRoot {
+			continue
+		}
+
+		functions, _ := p.pkgFunctions(pkg)
+		exported := make([]Function, len(functions))
+		for i, fn := range functions {
+			exported[i] = exportFunction(pkg, fn, i)
+			r.stats.Lines += strings.Count(fn.src, "\n")
+			r.stats.Bytes += len(fn.src)
+		}
+		r.Packages = append(r.Packages, Package{Name: pkg.Name, PkgPath: pkg.PkgPath, Functions: exported})
+		r.stats.Functions += len(functions)
+		r.stats.Packages++
+	}
+
+	return r, nil
+}
+
+// ParseJSON is like ParseStructured, but returns the packages of the ParseResult marshaled as a JSON array,
+// for tools consuming the extracted functions.
+func ParseJSON(funcPkgPath, funcName string, depth int) ([]byte, error) {
+	r, err := ParseStructured(funcPkgPath, funcName, depth)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(r.Packages)
+}
+
+// ParseJSON retrieves the specified function and its underlying functions as the JSON array of the packages of a ParseResult.
+func (m *ModuleParser) ParseJSON(funcName string, opts Options) ([]byte, error) {
+	r, err := m.ParseStructured(funcName, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(r.Packages)
+}
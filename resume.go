@@ -0,0 +1,81 @@
+package scparser
+
+// Resumption is the state of an incremental extraction, holding the processed functions and the functions
+// waiting to be processed. The functions are processed breadth-first, like with a MaxBytes limit.
+type Resumption struct {
+	p *parser
+}
+
+// ParseIncremental starts an incremental extraction of the specified function and its underlying functions,
+// processing at most budget functions. It returns the output so far, and a Resumption to continue the
+// extraction with Resume, which is nil once all functions are processed.
+func ParseIncremental(funcPkgPath, funcName string, opts Options, budget int) (string, *Resumption, error) {
+	m, err := newModuleParser(funcPkgPath, opts)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return m.ParseIncremental(funcName, opts, budget)
+}
+
+// ParseIncremental starts an incremental extraction of the specified function, see the package level ParseIncremental.
+func (m *ModuleParser) ParseIncremental(funcName string, opts Options, budget int) (string, *Resumption, error) {
+	funcObj, err := m.lookupFunction(funcName)
+	if err != nil {
+		return "", nil, err
+	}
+
+	p := newParser(m, opts)
+	p.incremental = true
+	p.rootSet[funcObj] = true
+	p.queue = []queuedFunction{{obj: funcObj, depth: rootDepth(opts)}}
+
+	output, r := Resume(&Resumption{p: p}, budget)
+
+	return output, r, nil
+}
+
+// Resume continues the extraction, processing at most budget more functions. It returns the output of all
+// functions processed so far, and the Resumption to continue with, or nil once all functions are processed.
+// With a MaxBytes limit, the limit applies to the source code added by each step, not to the output so far:
+// a function that no longer fits is left for the next step, and each step adds at least one function.
+// An extraction is cancelled by not resuming it.
+func Resume(r *Resumption, budget int) (string, *Resumption) {
+	p := r.p
+
+	// Count only the functions added by this step toward the MaxBytes limit
+	p.size = 0
+	for budget > 0 && len(p.queue) > 0 {
+		next := p.popQueue()
+		if _, ok := p.funcToFileAndPkg[next.obj]; !ok || p.seen[next.obj] {
+			continue
+		}
+
+		p.processFunction(next.obj, next.depth)
+		if p.truncated {
+			// Leave the function that did not fit for the next step
+			p.truncated = false
+			if !p.seen[next.obj] {
+				p.queue = append([]queuedFunction{next}, p.queue...)
+			}
+			break
+		}
+		budget--
+	}
+
+	// Drop the queued functions that are processed already, so a finished extraction is reported as such
+	pending := p.queue[:0]
+	for _, next := range p.queue {
+		if _, ok := p.funcToFileAndPkg[next.obj]; ok && !p.seen[next.obj] {
+			pending = append(pending, next)
+		}
+	}
+	p.queue = pending
+
+	output := p.toString(p.opts.ExcludeRoot, p.opts.CodeOnly)
+	if len(p.queue) == 0 {
+		return output, nil
+	}
+
+	return output, r
+}
@@ -1,14 +1,22 @@
 package scparser
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"go/ast"
+	goparser "go/parser"
 	"go/token"
 	"go/types"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 
+	"golang.org/x/mod/modfile"
 	"golang.org/x/tools/go/packages"
 )
 
@@ -17,171 +25,1103 @@ import (
 // arguments and returns a formatted string containing the combined source code.
 // The function will panic if the provided function is not found in the package path.
 func Parse(funcPkgPath, funcName string, excludeRoot, codeOnly bool) string {
-	// Change the working directory to the given package directory
-	changeBack := changeDir(funcPkgPath)
-	defer changeBack()
+	output, err := ParseE(funcPkgPath, funcName, excludeRoot, codeOnly)
+	panicOnErr(err)
 
-	funcSig, funcToFileAndPkg := initialize(funcName)
+	return output
+}
 
-	p := newParser(funcToFileAndPkg)
+// ParseE is like Parse, but returns a *ParseError instead of panicking if the go.mod file or the function
+// is not found, or the packages can not be loaded.
+func ParseE(funcPkgPath, funcName string, excludeRoot, codeOnly bool) (string, error) {
+	return parseE(funcPkgPath, funcName, Options{ExcludeRoot: excludeRoot, CodeOnly: codeOnly})
+}
 
-	// Process the function and its underlying functions up to a depth of 5 (or 6 if root is excluded)
-	if excludeRoot {
-		p.processFunction(funcSig, 6)
-	} else {
-		p.processFunction(funcSig, 5)
+// ParseWithDepth is like ParseE, but follows the underlying functions up to the given number of levels below
+// the root function, instead of the default of 4 levels used by Parse. A depth of 0 returns just the root
+// function, a negative depth is rejected with an error.
+func ParseWithDepth(funcPkgPath, funcName string, depth int, excludeRoot, codeOnly bool) (string, error) {
+	if depth < 0 {
+		return "", fmt.Errorf("invalid depth %d: must not be negative", depth)
+	}
+
+	return parseE(funcPkgPath, funcName, Options{MaxDepth: depth + 1, ExcludeRoot: excludeRoot, CodeOnly: codeOnly})
+}
+
+// parseE loads the module of the package directory and processes the function with the options,
+// returning a *ParseError if the module or function can not be found
+func parseE(funcPkgPath, funcName string, opts Options) (string, error) {
+	m, err := newModuleParser(funcPkgPath, opts)
+	if err != nil {
+		return "", &ParseError{PkgPath: funcPkgPath, FuncName: funcName, Err: err}
+	}
+
+	funcObj, err := m.lookupFunction(funcName)
+	if err != nil {
+		return "", &ParseError{PkgPath: funcPkgPath, FuncName: funcName, Err: err}
 	}
 
-	return p.toString(excludeRoot, codeOnly)
+	p := newParser(m, opts)
+	p.process(funcObj, rootDepth(opts))
+
+	return p.toString(opts.ExcludeRoot, opts.CodeOnly), nil
+}
+
+// ParseMany is like Parse, but retrieves the combined source code of several root functions of the package.
+// The roots share the processed functions, so common underlying functions appear only once, in the package
+// declaring them. The function will panic if one of the functions is not found in the package path.
+func ParseMany(funcPkgPath string, funcNames []string, excludeRoot, codeOnly bool) string {
+	m, err := newModuleParser(funcPkgPath, Options{})
+	panicOnErr(err)
+
+	return m.ParseMany(funcNames, Options{ExcludeRoot: excludeRoot, CodeOnly: codeOnly})
+}
+
+// ParseWithOptions is like Parse, but takes the output and traversal settings from opts.
+func ParseWithOptions(funcPkgPath, funcName string, opts Options) string {
+	m, err := newModuleParser(funcPkgPath, opts)
+	panicOnErr(err)
+
+	return m.ParseWithOptions(funcName, opts)
+}
+
+// ReachablePackages returns the import paths of the packages reached from the specified function,
+// without extracting any source code.
+func ReachablePackages(funcPkgPath, funcName string, opts Options) ([]string, error) {
+	m, err := newModuleParser(funcPkgPath, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.ReachablePackages(funcName, opts)
+}
+
+// ParseImplementors retrieves the source code of the methods of every go.mod type implementing the
+// interface with the given import path and name, and their underlying functions, within the Go module
+// packages of the given package directory.
+func ParseImplementors(funcPkgPath, interfacePath, interfaceName string, opts Options) (string, error) {
+	m, err := newModuleParser(funcPkgPath, opts)
+	if err != nil {
+		return "", err
+	}
+
+	return m.ParseImplementors(interfacePath, interfaceName, opts)
 }
 
 type parser struct {
+	// ModuleParser holds the loaded go.mod packages and their functions
+	*ModuleParser
+
+	// funcToFileAndPkg is the file and package of each function of the module. With IncludeModuleCacheSource it is
+	// a copy, to which the functions loaded from the module cache are added, so the module itself is not changed.
+	funcToFileAndPkg map[*types.Func]fileAndPkg
+
 	// rootPkg is the root package of the Go module
 	rootPkg *packages.Package
 
-	// funcToFileAndPkg is a map that stores the file and package for each function signature
-	funcToFileAndPkg map[*types.Signature]fileAndPkg
+	// functions is a map of packages to their processed functions, in the order they were processed
+	functions map[*packages.Package][]function
 
-	// functions is a map of packages to their function source code
-	functions map[*packages.Package]string
-
-	// pkgOrder is an ordered list of processed packages to maintain the order of processing
+	// pkgOrder is an ordered list of processed packages to maintain the order of processing. Each package is listed
+	// once, when its first function is processed, so the functions of a package are rendered together in one block
+	// even if the traversal returns to it from another package.
 	pkgOrder []*packages.Package
 
+	// rootOmitted is set if the package of the root function is left out of pkgOrder, so the first package is
+	// rendered with its package header too
+	rootOmitted bool
+
 	// seen is a map to keep track of already processed functions
-	seen map[*types.Signature]bool
+	seen map[*types.Func]bool
+
+	// depths holds the largest remaining depth each processed function was processed with, so a function
+	// reached again on a shorter path is expanded again
+	depths map[*types.Func]int
+
+	// rootSet holds the root functions, which are always included regardless of the options leaving out or
+	// shortening the underlying functions
+	rootSet map[*types.Func]bool
+
+	// seenTypes is a map to keep track of already processed type declarations
+	seenTypes map[*types.TypeName]bool
+
+	// seenValues is a map to keep track of already processed constant and variable declarations
+	seenValues map[types.Object]bool
+
+	// seenAssertions is a map to keep track of types of which the interface assertions are processed
+	seenAssertions map[*types.TypeName]bool
+
+	// external is an ordered list of called functions declared outside the loaded go.mod packages
+	external []*types.Func
+
+	// seenExternal is a map to keep track of already recorded external functions
+	seenExternal map[*types.Func]bool
+
+	opts Options
+
+	// includeOnly is the set of qualified names of the functions of which the body is included, nil includes all
+	includeOnly map[string]bool
+
+	// ifaceOrder is the interface of the InterfaceOrder option, nil if none
+	ifaceOrder *interfaceOrder
+
+	// levels holds the level in the call tree at which functions are followed, the root functions are at level 1
+	levels map[*types.Func]int
+
+	// level is the level of the function of which the underlying functions are being processed
+	level int
+
+	// caller is the qualified name of the function of which the underlying functions are being processed
+	caller string
+
+	// calls holds the qualified names of the callers and the underlying functions followed from them,
+	// in the order they were first followed
+	calls [][2]string
+
+	// seenCalls is a map to keep track of the recorded calls
+	seenCalls map[[2]string]bool
+
+	// queue holds the functions waiting to be processed when processing breadth-first
+	queue []queuedFunction
+
+	// incremental queues the underlying functions, so they can be processed in steps by a Resumption
+	incremental bool
+
+	// callees collects the underlying functions of the function being processed when MaxCalleesPerFunction is set
+	callees *[]queuedFunction
+
+	// size is the total size in bytes of the processed function source code
+	size int
+
+	// errs are the errors of declarations that could not be rendered
+	errs []error
+
+	// roots are the qualified names of the processed root functions
+	roots []string
+
+	// commentMaps caches the comment maps of the files of which the leading comments of functions are extracted
+	commentMaps map[*ast.File]ast.CommentMap
+
+	// fileHints caches the scparser:impl hints of the files of which the underlying functions are processed
+	fileHints map[*ast.File]map[int][]string
+
+	// read caches the content of the source files read from disk, which were not parsed when loading the packages,
+	// guarded by readMu as they are read concurrently with Concurrency
+	read   map[string][]byte
+	readMu sync.Mutex
+
+	// prepared holds the declarations and source code of the queued functions prepared with Concurrency
+	prepared map[*types.Func]preparedFunction
+
+	// bodyHashes caches the hashes of the normalized function signatures and bodies compared by DedupBySource
+	bodyHashes map[*ast.FuncDecl]string
+
+	// stream writes the functions as they are processed instead of keeping them, nil if not streaming
+	stream *streamWriter
+
+	// noSource skips extracting the source code of the processed declarations, only recording where they are
+	noSource bool
+
+	// truncated reports whether functions were left out because of the MaxBytes limit
+	truncated bool
+
+	// ctx stops the processing of further functions once it is done, nil if it can not be canceled
+	ctx context.Context
+}
+
+// queuedFunction is a function waiting to be processed at the given depth
+type queuedFunction struct {
+	obj   *types.Func
+	depth int
+}
+
+// function is a processed function declaration together with its qualified name and extracted source code.
+// decl is nil for the type, constant and variable declarations that are included alongside the functions.
+type function struct {
+	decl *ast.FuncDecl
+	name string
+	src  string
+
+	// start and end are the positions the declaration starts and ends at
+	start token.Position
+	end   token.Position
+
+	// level is the level of the function in the call tree, 0 for other declarations
+	level int
+
+	// recv is the name of the receiver type of a method, or of the declared type, used to group the output
+	recv string
+
+	// node is the syntax the source code is extracted from, used to find the imports it needs, nil for notes
+	node ast.Node
+
+	// root reports whether the function is a root function, which is always included
+	root bool
 }
 
 // fileAndPkg is a struct that contains a pointer to an ast.File and a pointer to a packages.Package
 type fileAndPkg struct {
 	file *ast.File
 	pkg  *packages.Package
+
+	// exported reports whether the name of the function declared in the file is exported
+	exported bool
 }
 
-func newParser(funcToFileAndPkg map[*types.Signature]fileAndPkg) *parser {
+func newParser(m *ModuleParser, opts Options) *parser {
+	var includeOnly map[string]bool
+	if len(opts.IncludeOnlyFunctions) > 0 {
+		includeOnly = make(map[string]bool)
+		for _, name := range opts.IncludeOnlyFunctions {
+			includeOnly[name] = true
+		}
+	}
+
+	funcToFileAndPkg := m.funcToFileAndPkg
+	if opts.IncludeModuleCacheSource {
+		funcToFileAndPkg = make(map[*types.Func]fileAndPkg, len(m.funcToFileAndPkg))
+		for obj, f := range m.funcToFileAndPkg {
+			funcToFileAndPkg[obj] = f
+		}
+	}
+
+	var ifaceOrder *interfaceOrder
+	if opts.InterfaceOrder != `` {
+		ifaceOrder = m.interfaceOrder(opts.InterfaceOrder)
+	}
+
 	return &parser{
+		ModuleParser:     m,
 		funcToFileAndPkg: funcToFileAndPkg,
-		functions:        make(map[*packages.Package]string),
-		seen:             make(map[*types.Signature]bool),
+		functions:        make(map[*packages.Package][]function),
+		seen:             make(map[*types.Func]bool),
+		depths:           make(map[*types.Func]int),
+		rootSet:          make(map[*types.Func]bool),
+		levels:           make(map[*types.Func]int),
+		bodyHashes:       make(map[*ast.FuncDecl]string),
+		read:             make(map[string][]byte),
+		prepared:         make(map[*types.Func]preparedFunction),
+		commentMaps:      make(map[*ast.File]ast.CommentMap),
+		fileHints:        make(map[*ast.File]map[int][]string),
+		seenTypes:        make(map[*types.TypeName]bool),
+		seenExternal:     make(map[*types.Func]bool),
+		seenAssertions:   make(map[*types.TypeName]bool),
+		seenValues:       make(map[types.Object]bool),
+		seenCalls:        make(map[[2]string]bool),
+		opts:             opts,
+		includeOnly:      includeOnly,
+		ifaceOrder:       ifaceOrder,
 	}
 }
 
+// process processes the root function and its underlying functions up to the specified depth.
+// With a MaxBytes limit the functions are processed breadth-first, otherwise depth-first.
+func (p *parser) process(funcObj *types.Func, depth int) {
+	p.rootSet[funcObj] = true
+	p.processFunction(funcObj, depth)
+	p.processQueue()
+}
+
+// processRoots processes each root function and its underlying functions up to the specified depth.
+// The roots share the processed functions, so common underlying functions appear only once.
+func (p *parser) processRoots(funcObjs []*types.Func, depth int) {
+	for _, funcObj := range funcObjs {
+		p.process(funcObj, depth)
+	}
+}
+
+// processQueue processes the queued functions until the queue is empty or the output is truncated.
+// The functions are processed in the order they were queued, except that among the functions queued
+// at the same depth as the first queued function, the exported functions are processed first.
+func (p *parser) processQueue() {
+	for len(p.queue) > 0 && !p.truncated {
+		next := p.popQueue()
+		p.processFunction(next.obj, next.depth)
+	}
+}
+
+// popQueue removes and returns the next queued function to process
+func (p *parser) popQueue() queuedFunction {
+	i := p.nextQueued()
+	next := p.queue[i]
+	p.queue = append(p.queue[:i], p.queue[i+1:]...)
+
+	return next
+}
+
+// nextQueued returns the index of the first exported function queued at the depth of the first queued function,
+// or 0 if there is none
+func (p *parser) nextQueued() int {
+	depth := p.queue[0].depth
+	for i, next := range p.queue {
+		if next.depth == depth && !p.seen[next.obj] && p.funcToFileAndPkg[next.obj].exported {
+			return i
+		}
+	}
+
+	return 0
+}
+
+// followFunction processes an underlying function, or queues it when processing breadth-first.
+// While the callees of a function are collected, the function is added to them instead.
+func (p *parser) followFunction(funcObj *types.Func, depth int) {
+	// Functions of the universe scope, like the Error method of the error interface, have no package
+	if funcObj.Pkg() == nil {
+		return
+	}
+
+	funcObj = funcObj.Origin()
+	if p.tests {
+		funcObj = p.testVariantFunc(funcObj)
+	}
+
+	// Abstract methods of interfaces have no body, follow the implementations instead. This applies to
+	// calls as well as to method values, like `apply(r.Read)`.
+	if iface := abstractMethodOf(funcObj); iface != nil && p.opts.ResolveInterfaces {
+		p.processImplementations(iface, funcObj.Name(), depth)
+		return
+	}
+
+	// Functions outside the IncludePrefixes are not followed, or only included as a leaf with their signature
+	if !p.withinPrefixes(funcObj.Pkg().Path()) {
+		if !p.opts.SignaturesOutsidePrefixes {
+			return
+		}
+		depth = 1
+	}
+
+	// Processed functions, like the functions of a cycle of recursive calls, are skipped before they are collected
+	// or queued, so they don't take up a callee of MaxCalleesPerFunction or a place in the queue. Only functions
+	// reached on a shorter path, with more depth left than they were processed with, are followed again.
+	if p.seen[funcObj] && depth <= p.depths[funcObj] {
+		p.recordCall(funcObj)
+		return
+	}
+
+	if p.callees != nil {
+		*p.callees = append(*p.callees, queuedFunction{obj: funcObj, depth: depth})
+		return
+	}
+
+	if level, ok := p.levels[funcObj]; !ok || p.level+1 < level {
+		p.levels[funcObj] = p.level + 1
+	}
+	p.recordCall(funcObj)
+
+	if p.opts.MaxBytes > 0 || p.incremental {
+		p.queue = append(p.queue, queuedFunction{obj: funcObj, depth: depth})
+		return
+	}
+
+	p.processFunction(funcObj, depth)
+}
+
 // Convert functions into one string
 func (p *parser) toString(excludeRoot, codeOnly bool) string {
-	var result string
+	var sb strings.Builder
+	_ = p.writeTo(&sb, excludeRoot, codeOnly)
+
+	return sb.String()
+}
+
+// writeTo writes the functions to w one package block at a time, the same way toString formats them,
+// and returns the first write error
+func (p *parser) writeTo(w io.Writer, excludeRoot, codeOnly bool) error {
+	bw := &blockWriter{w: w}
+	if p.opts.IncludePreamble {
+		bw.writeString(p.preamble() + "\n")
+	}
+	// The sections following the functions are separated from the functions, not from the preamble
+	bw.written = false
+
+	if p.opts.Flatten {
+		bw.writeString(p.flatString(excludeRoot, codeOnly))
+	} else {
+		for k, pkg := range p.pkgOrder {
+			if k == 0 && excludeRoot {
+				continue
+			}
+			if k > 1 || (k == 1 && !excludeRoot) || (k == 0 && p.rootOmitted) {
+				bw.writeString(formatPkgHeader(p.opts, pkg, codeOnly) + "\n")
+			}
+			bw.writeString(p.formatPkgFunctions(pkg, codeOnly))
+			if k < len(p.pkgOrder)-1 {
+				bw.writeString("\n\n")
+			}
+		}
+	}
+
+	for _, section := range p.sections(codeOnly) {
+		if bw.written {
+			bw.writeString("\n\n")
+		}
+		bw.writeString(section)
+	}
+
+	if p.truncated {
+		bw.writeString("\n\n" + p.truncationNote(codeOnly))
+	}
+
+	return bw.err
+}
+
+// sections returns the formatted sections following the functions: the tests and the external functions
+func (p *parser) sections(codeOnly bool) []string {
+	var sections []string
+	if p.opts.IncludeTests {
+		if tests := p.testFunctions(); tests != "" {
+			sections = append(sections, p.formatFunctions(tests, codeOnly))
+		}
+	}
+
+	if len(p.external) > 0 && p.opts.StubExternal {
+		sections = append(sections, p.formatFunctions(p.externalStubs(), codeOnly))
+	}
+
+	if len(p.external) > 0 && p.opts.ExternalCalls {
+		sections = append(sections, p.formatFunctions(p.externalCalls(), codeOnly))
+	}
+
+	return sections
+}
+
+// truncationNote returns the note marking the output as truncated by the MaxBytes limit
+func (p *parser) truncationNote(codeOnly bool) string {
+	return formatNote(fmt.Sprintf("Truncated: output exceeds the maximum of %d bytes", p.opts.MaxBytes), codeOnly)
+}
+
+// flatString converts the functions of all packages into one block without package headers
+func (p *parser) flatString(excludeRoot, codeOnly bool) string {
+	var functions string
+	var blocks []string
 	for k, pkg := range p.pkgOrder {
 		if k == 0 && excludeRoot {
 			continue
 		}
-		if k > 1 || (k == 1 && !excludeRoot) {
-			result += formatPkg(pkg.Name, codeOnly) + "\n"
+		functions += p.joinPkgFunctions(pkg)
+		blocks = append(blocks, p.formatPkgFunctions(pkg, codeOnly))
+	}
+
+	// The fenced blocks per function already form one sequence without package headers
+	if p.fenceFunctions(codeOnly) {
+		return strings.Join(blocks, "\n\n")
+	}
+
+	if functions == "" {
+		return ""
+	}
+
+	return p.formatFunctions(functions, codeOnly)
+}
+
+// fenceFunctions reports whether each function is formatted as a fenced block of its own
+func (p *parser) fenceFunctions(codeOnly bool) bool {
+	return p.opts.FenceFunctions && !codeOnly
+}
+
+// formatPkgFunctions formats the functions of the package as one block, or with FenceFunctions as a
+// fenced block per function with its qualified name in the info string, e.g. ```go title=github.com/x/y.Func
+func (p *parser) formatPkgFunctions(pkg *packages.Package, codeOnly bool) string {
+	if !p.fenceFunctions(codeOnly) {
+		return p.formatFunctions(p.joinPkgFunctions(pkg), codeOnly)
+	}
+
+	functions, omitted := p.pkgFunctions(pkg)
+	blocks := make([]string, 0, len(functions)+1)
+	if imports := p.pkgImports(pkg, functions); imports != "" {
+		blocks = append(blocks, "```"+fenceLanguage(p.opts)+"\n"+imports+"```")
+	}
+	for _, fn := range functions {
+		blocks = append(blocks, "```"+fenceLanguage(p.opts)+" title="+fn.name+"\n"+fn.src+"```")
+	}
+
+	result := strings.Join(blocks, "\n\n")
+	if omitted > 0 {
+		result += fmt.Sprintf("\n\n(%d more files omitted)", omitted)
+	}
+
+	return result
+}
+
+// pkgFunctions returns the ordered functions of the package to include, and the number of files omitted by MaxFilesPerPackage
+func (p *parser) pkgFunctions(pkg *packages.Package) ([]function, int) {
+	functions, omitted := p.limitFiles(p.orderFunctions(pkg, p.orderByInterface(pkg, p.dedupBySource(pkg, p.functions[pkg]))))
+
+	return p.annotateDepths(functions), omitted
+}
+
+// joinPkgFunctions joins the source code of the functions of the package. With MaxFilesPerPackage,
+// only the functions of the first files are included, followed by a comment with the number of omitted files.
+func (p *parser) joinPkgFunctions(pkg *packages.Package) string {
+	functions, omitted := p.pkgFunctions(pkg)
+
+	var result string
+	if p.opts.GroupByReceiver {
+		result = joinGroupedFunctions(functions)
+	} else {
+		result = joinFunctions(functions)
+	}
+
+	if omitted > 0 {
+		result += fmt.Sprintf("\n// (%d more files omitted)\n", omitted)
+	}
+
+	if imports := p.pkgImports(pkg, functions); imports != "" {
+		result = "\n" + imports + result
+	}
+
+	return result
+}
+
+// limitFiles returns the functions of the first MaxFilesPerPackage files, and the number of omitted files
+func (p *parser) limitFiles(functions []function) ([]function, int) {
+	if p.opts.MaxFilesPerPackage <= 0 {
+		return functions, 0
+	}
+
+	var included []function
+	files := make(map[string]bool)
+	omitted := make(map[string]bool)
+	for _, fn := range functions {
+		if !files[fn.start.Filename] && len(files) >= p.opts.MaxFilesPerPackage {
+			omitted[fn.start.Filename] = true
+			continue
+		}
+		files[fn.start.Filename] = true
+		included = append(included, fn)
+	}
+
+	return included, len(omitted)
+}
+
+// joinGroupedFunctions joins the source code of the functions grouped by receiver type, each group preceded
+// by a header comment. Functions without a receiver come first, followed by the receiver types in the order
+// they were first processed.
+func joinGroupedFunctions(functions []function) string {
+	var recvOrder []string
+	groups := make(map[string][]function)
+	for _, fn := range functions {
+		if _, ok := groups[fn.recv]; !ok && fn.recv != "" {
+			recvOrder = append(recvOrder, fn.recv)
 		}
-		result += formatFunctions(p.functions[pkg], codeOnly)
-		if k < len(p.pkgOrder)-1 {
-			result += "\n\n"
+		groups[fn.recv] = append(groups[fn.recv], fn)
+	}
+
+	var result string
+	if len(groups[""]) > 0 {
+		result += "\n// functions\n" + joinFunctions(groups[""])
+	}
+	for _, recv := range recvOrder {
+		result += "\n// type " + recv + "\n" + joinFunctions(groups[recv])
+	}
+
+	return result
+}
+
+// joinFunctions joins the source code of the functions, each preceded by a newline
+func joinFunctions(functions []function) string {
+	var result string
+	for _, fn := range functions {
+		result += "\n" + fn.src
+	}
+
+	return result
+}
+
+// formatPkgHeader formats the header of the block of the package with the PackageHeader option, or formatPkg by default
+func formatPkgHeader(opts Options, pkg *packages.Package, codeOnly bool) string {
+	if opts.PackageHeader != nil {
+		return opts.PackageHeader(pkg)
+	}
+
+	return formatPkg(pkg.Name, codeOnly)
+}
+
+func formatPkg(pkgName string, codeOnly bool) string {
+	if codeOnly {
+		return `// ` + pkgName
+	}
+
+	return pkgName
+}
+
+func formatNote(note string, codeOnly bool) string {
+	if codeOnly {
+		return `// ` + note
+	}
+
+	return note
+}
+
+// formatFunctions wraps the functions in a fenced block with the FenceLanguage info string, unless codeOnly is set
+func (p *parser) formatFunctions(functions string, codeOnly bool) string {
+	if codeOnly {
+		return functions
+	}
+
+	return "```" + fenceLanguage(p.opts) + functions + "```"
+}
+
+// fenceLanguage returns the language of the opening fences, FenceLanguage or go by default
+func fenceLanguage(opts Options) string {
+	if opts.FenceLanguage == `` {
+		return `go`
+	}
+
+	return opts.FenceLanguage
+}
+
+// processFunction processes a function with the provided function object, and its underlying functions up to the specified depth
+func (p *parser) processFunction(funcObj *types.Func, depth int) {
+	if p.ctx != nil && p.ctx.Err() != nil {
+		return
+	}
+
+	// Check if the function has already been processed
+	// If so, return early to avoid processing it again, only expanding it again if there is more depth left
+	if p.seen[funcObj] {
+		if depth > p.depths[funcObj] {
+			p.expandFunction(funcObj, depth)
+		}
+		return
+	}
+
+	// Try to get the file and package information associated with the function
+	// Return if the function is not found in the map (i.e. not in a go mod package)
+	f, ok := p.funcToFileAndPkg[funcObj]
+	if !ok {
+		return
+	}
+
+	// Skip the functions of excluded packages, so nothing beneath them is followed either
+	if !p.rootSet[funcObj] && hasPathPrefix(p.opts.ExcludePrefixes, f.pkg.PkgPath) {
+		return
+	}
+
+	// Skip the unexported functions with ExportedOnly, without following their underlying functions
+	if p.opts.ExportedOnly && !p.rootSet[funcObj] && !f.exported {
+		return
+	}
+	level := p.levelOf(funcObj)
+
+	// Function literals of package level variables are not declared in the file
+	if fn, ok := p.varFuncLits[funcObj]; ok {
+		p.processDecl(f, fn, funcObj, depth, level)
+		return
+	}
+
+	// Use the declaration prepared with Concurrency, or look it up in the file
+	if prep, ok := p.prepared[funcObj]; ok && prep.decl != nil {
+		p.processDecl(f, prep.decl, funcObj, depth, level)
+		return
+	}
+	if fn := declOf(f, funcObj); fn != nil {
+		p.processDecl(f, fn, funcObj, depth, level)
+	}
+}
+
+// expandFunction follows the underlying functions of a processed function again, with the larger remaining depth
+// it is reached with on a shorter path. The function itself is already included.
+func (p *parser) expandFunction(funcObj *types.Func, depth int) {
+	p.depths[funcObj] = depth
+
+	f := p.funcToFileAndPkg[funcObj]
+	fn, ok := p.varFuncLits[funcObj]
+	if !ok {
+		fn = declOf(f, funcObj)
+	}
+	if fn == nil {
+		return
+	}
+
+	if p.opts.SkipGeneratedWrappers && !p.rootSet[funcObj] && isGenerated(f.file) {
+		if delegate := p.delegateOf(f.pkg, fn); delegate != nil {
+			p.processFunction(delegate, depth)
+			return
+		}
+	}
+
+	p.processUnderlyingFunctions(f, fn, depth-1, p.levelOf(funcObj))
+}
+
+// declOf returns the declaration of the function in its file, or nil if it is not found
+func declOf(f fileAndPkg, funcObj *types.Func) *ast.FuncDecl {
+	var decl *ast.FuncDecl
+
+	// Inspect the AST (Abstract Syntax Tree) of the file
+	ast.Inspect(f.file, func(n ast.Node) bool {
+		if decl != nil {
+			return false
+		}
+
+		// Check if the node is a function declaration
+		fn, ok := n.(*ast.FuncDecl)
+		if !ok || fn.Name == nil {
+			return true
+		}
+
+		// Check if the declared function is the target function
+		if f.pkg.TypesInfo.ObjectOf(fn.Name) == funcObj {
+			decl = fn
+		}
+
+		// Return false to stop AST traversal once the target function is found
+		return false
+	})
+
+	return decl
+}
+
+// processDecl processes the declaration of the given function at the given level in the call tree,
+// and its underlying functions up to the specified depth
+func (p *parser) processDecl(f fileAndPkg, fn *ast.FuncDecl, funcObj *types.Func, depth, level int) {
+	// See through generated wrappers by processing the function they delegate to in their place
+	if p.opts.SkipGeneratedWrappers && !p.rootSet[funcObj] && isGenerated(f.file) {
+		if delegate := p.delegateOf(f.pkg, fn); delegate != nil {
+			p.seen[funcObj] = true
+			p.depths[funcObj] = depth
+			if _, ok := p.levels[delegate]; !ok {
+				p.levels[delegate] = level
+			}
+			p.processWrapper(f.pkg, fn, delegate, depth)
+			return
+		}
+	}
+
+	// Omit trivial functions from the output, but still process their underlying functions
+	if !p.rootSet[funcObj] && bodyLines(f.pkg.Fset, fn) < p.opts.MinFunctionLines {
+		p.seen[funcObj] = true
+		p.depths[funcObj] = depth
+		p.processUnderlyingFunctions(f, fn, depth-1, level)
+		return
+	}
+
+	// Extract the source code of the function, or only its doc comments and signature
+	extract := p.extractFunction
+	if prep, ok := p.prepared[funcObj]; ok && prep.decl == fn {
+		extract = prep.extracted
+	}
+	var node ast.Node = fn
+	name := qualifiedName(f.pkg, fn)
+	if p.signatureOnly(name, f.pkg.PkgPath, level, p.rootSet[funcObj]) {
+		extract = p.extractSignature
+		node = fn.Type
+	}
+	funcSrc := p.render(name, func() (string, error) {
+		src, err := extract(f.pkg.Fset, fn)
+		if err != nil || !p.opts.IncludeLeadingComments {
+			return src, err
+		}
+
+		leading, err := p.leadingComments(f.pkg.Fset, f.file, fn)
+		return leading + src, err
+	})
+	if p.opts.MarkPanicRecover && callsPanicOrRecover(f.pkg.TypesInfo, fn) {
+		funcSrc = panicRecoverMarker + funcSrc
+	}
+
+	funcSig := funcObj.Type().(*types.Signature)
+	var recv string
+	if funcSig.Recv() != nil {
+		if named := namedType(funcSig.Recv().Type()); named != nil {
+			recv = named.Obj().Name()
+		}
+	}
+
+	pos := f.pkg.Fset.Position(fn.Pos())
+	if !p.addFunction(f.pkg, function{decl: fn, name: name, start: pos, end: f.pkg.Fset.Position(fn.End()), src: funcSrc, recv: recv, level: level, node: node, root: p.rootSet[funcObj]}) {
+		return
+	}
+
+	// The index of the function, to mark the omitted callees below it after the declarations included alongside it
+	i := len(p.functions[f.pkg]) - 1
+
+	// Add the function to the map of processed functions
+	p.seen[funcObj] = true
+	p.depths[funcObj] = depth
+	if level == 1 {
+		p.roots = append(p.roots, name)
+	}
+
+	// Include the declaration of the receiver type, and its interface assertions
+	if funcSig.Recv() != nil {
+		if named := namedType(funcSig.Recv().Type()); named != nil {
+			if p.opts.IncludeReceiverTypes {
+				p.processTypeDecl(named.Obj())
+			}
+			if p.opts.InterfaceAssertions {
+				p.processAssertions(named.Obj())
+			}
+		}
+	}
+
+	// Include the declarations referenced by the function after it
+	if p.opts.IncludeReferencedDecls {
+		p.processReferencedDecls(f.pkg.TypesInfo, node)
+	}
+
+	// Process the underlying functions, marking the callees omitted by MaxCalleesPerFunction below the function
+	if omitted := p.processUnderlyingFunctions(f, fn, depth-1, level); omitted > 0 && i >= 0 {
+		note := fmt.Sprintf("// (%d more callees omitted)\n", omitted)
+		p.functions[f.pkg][i].src += note
+		p.size += len(note)
+	}
+}
+
+// signatureOnly reports whether only the doc comments and signature of the function are included, for DocsOnly,
+// IncludeOnlyFunctions, SignaturesOutsidePrefixes and SignatureOnlyBelowDepth. Only the first two apply to root
+// functions.
+func (p *parser) signatureOnly(name, pkgPath string, level int, root bool) bool {
+	if p.opts.DocsOnly || (p.includeOnly != nil && !p.includeOnly[name]) {
+		return true
+	}
+	if root {
+		return false
+	}
+
+	return !p.withinPrefixes(pkgPath) || (p.opts.SignatureOnlyBelowDepth > 0 && level > p.opts.SignatureOnlyBelowDepth)
+}
+
+// levelOf returns the level in the call tree at which the function is followed, 1 for root functions
+func (p *parser) levelOf(funcObj *types.Func) int {
+	if level, ok := p.levels[funcObj]; ok {
+		return level
+	}
+
+	return 1
+}
+
+// render extracts source code with the given function. If the extraction fails, the error is recorded
+// and a placeholder comment is returned instead, so a single declaration does not discard the entire output.
+// Nothing is extracted when the source code is skipped.
+func (p *parser) render(name string, extract func() (string, error)) (src string) {
+	if p.noSource {
+		return ""
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			src = p.renderError(name, fmt.Errorf("%v", r))
 		}
+	}()
+
+	src, err := extract()
+	if err != nil {
+		return p.renderError(name, err)
 	}
 
-	return result
+	return src
 }
 
-func formatPkg(pkgName string, codeOnly bool) string {
-	if codeOnly {
-		return `// ` + pkgName
+// renderError records the error of rendering the named declaration and returns a placeholder comment
+func (p *parser) renderError(name string, err error) string {
+	err = fmt.Errorf("error rendering %s: %w", name, err)
+	p.errs = append(p.errs, err)
+
+	return "// <" + err.Error() + ">\n"
+}
+
+// qualifiedName returns the fully qualified name of the function declaration,
+// e.g. `github.com/x/y.Func` or `(*github.com/x/y.Type).Method`
+func qualifiedName(pkg *packages.Package, fn *ast.FuncDecl) string {
+	if obj, ok := pkg.TypesInfo.ObjectOf(fn.Name).(*types.Func); ok {
+		return obj.FullName()
 	}
 
-	return pkgName
+	return pkg.PkgPath + `.` + fn.Name.Name
 }
 
-func formatFunctions(functions string, codeOnly bool) string {
-	if codeOnly {
-		return functions
+// bodyLines returns the number of lines between the braces of the function body,
+// a body on a single line counts as one line
+func bodyLines(fset *token.FileSet, fn *ast.FuncDecl) int {
+	if fn.Body == nil {
+		return 0
+	}
+
+	lbrace := fset.Position(fn.Body.Lbrace).Line
+	rbrace := fset.Position(fn.Body.Rbrace).Line
+	if lbrace == rbrace {
+		return 1
 	}
 
-	return "```" + functions + "```"
+	return rbrace - lbrace - 1
 }
 
-// processFunction processes a function with the provided package path and signature, and its underlying functions up to the specified depth
-func (p *parser) processFunction(funcSig *types.Signature, depth int) {
-	// Check if the function signature has already been processed
-	// If so, return early to avoid processing it again
-	if p.seen[funcSig] {
-		return
+// addFunction adds the function to the functions of the package.
+// It returns false if the function no longer fits in the MaxBytes limit, or an earlier one did not fit,
+// root functions and the first function of a step of an incremental extraction are always kept.
+func (p *parser) addFunction(pkg *packages.Package, fn function) bool {
+	if !fn.root && (p.truncated || (p.opts.MaxBytes > 0 && p.size+len(fn.src)+1 > p.opts.MaxBytes && !(p.incremental && p.size == 0))) {
+		p.truncated = true
+		return false
 	}
+	p.size += len(fn.src) + 1
 
-	// Try to get the file and package information associated with the function signature
-	// Return if the function signature is not found in the map (i.e. not in a go mod package)
-	f, ok := p.funcToFileAndPkg[funcSig]
-	if !ok {
+	// Write the function right away when streaming, without keeping it
+	if p.stream != nil {
+		p.stream.write(pkg, fn)
+		return true
+	}
+
+	// If the package is not yet in the functions map, add it to the pkgOrder list
+	if _, ok := p.functions[pkg]; !ok {
+		p.pkgOrder = append(p.pkgOrder, pkg)
+	}
+
+	// Append the function to the existing functions for the package
+	p.functions[pkg] = append(p.functions[pkg], fn)
+
+	return true
+}
+
+// processTypeDecl adds the declaration of the named type to the output, if it is declared in a go mod package
+func (p *parser) processTypeDecl(obj *types.TypeName) {
+	if p.seenTypes[obj] {
 		return
 	}
 
-	// Inspect the AST (Abstract Syntax Tree) of the file
-	ast.Inspect(f.file, func(n ast.Node) bool {
-		// Check if the node is a function declaration
-		fn, ok := n.(*ast.FuncDecl)
-		if !ok || fn.Name == nil {
-			return true
+	for _, pkg := range p.pkgs {
+		if obj.Pkg() == nil || pkg.PkgPath != obj.Pkg().Path() {
+			continue
 		}
 
-		// Check if the function signature matches the target function signature
-		sig, ok := f.pkg.TypesInfo.ObjectOf(fn.Name).Type().(*types.Signature)
-		if !ok || sig != funcSig {
-			return true
-		}
+		for _, file := range pkg.Syntax {
+			for _, decl := range file.Decls {
+				gd, ok := decl.(*ast.GenDecl)
+				if !ok || gd.Tok != token.TYPE {
+					continue
+				}
 
-		// Extract the source code of the function
-		funcSrc, err := extractSourceCode(f.pkg.Fset, f.file, fn)
-		if err != nil {
-			panic(err)
-		}
+				for _, spec := range gd.Specs {
+					ts, ok := spec.(*ast.TypeSpec)
+					if !ok || pkg.TypesInfo.Defs[ts.Name] != obj {
+						continue
+					}
+
+					name := obj.Pkg().Path() + `.` + obj.Name()
+					src := p.render(name, func() (string, error) {
+						return p.specSource(pkg.Fset, gd, ts)
+					})
 
-		// If the package is not yet in the functions map, add it to the pkgOrder list
-		if _, ok := p.functions[f.pkg]; !ok {
-			p.pkgOrder = append(p.pkgOrder, f.pkg)
+					pos := pkg.Fset.Position(ts.Pos())
+					if p.addFunction(pkg, function{name: name, start: pos, end: pkg.Fset.Position(ts.End()), src: src, recv: obj.Name(), node: ts}) {
+						p.seenTypes[obj] = true
+						if p.opts.InterfaceAssertions {
+							p.processAssertions(obj)
+						}
+					}
+					return
+				}
+			}
 		}
+	}
+}
 
-		// Append the extracted function source code to the existing source code for the package, separated by a newline
-		p.functions[f.pkg] += "\n" + funcSrc
+// specSource extracts the source code of the declaration of a spec.
+// A spec in a grouped declaration is extracted on its own, wrapped in a new group.
+func (p *parser) specSource(fset *token.FileSet, gd *ast.GenDecl, spec ast.Spec) (string, error) {
+	if !gd.Lparen.IsValid() {
+		return p.extractSourceCode(fset, gd, gd.Doc)
+	}
 
-		// Add the function to the map of processed functions
-		p.seen[funcSig] = true
+	var doc *ast.CommentGroup
+	switch spec := spec.(type) {
+	case *ast.TypeSpec:
+		doc = spec.Doc
+	case *ast.ValueSpec:
+		doc = spec.Doc
+	}
 
-		// Process the underlying functions
-		p.processUnderlyingFunctions(f.pkg, fn, depth-1)
+	src, err := p.extractSourceCode(fset, spec, doc)
+	if err != nil {
+		return "", err
+	}
 
-		// Return false to stop AST traversal once the target function is found and processed
-		return false
-	})
+	return gd.Tok.String() + " (\n" + src + ")\n", nil
 }
 
-// processUnderlyingFunctions processes the underlying functions called within the given function up to a specified depth
-func (p *parser) processUnderlyingFunctions(pkg *packages.Package, fn *ast.FuncDecl, depth int) {
+// processUnderlyingFunctions processes the underlying functions called within the given function up to a specified depth.
+// The level is the level of the function in the call tree. It returns the number of callees omitted because
+// of the MaxCalleesPerFunction limit.
+func (p *parser) processUnderlyingFunctions(f fileAndPkg, fn *ast.FuncDecl, depth, level int) int {
+	prevLevel, prevCaller := p.level, p.caller
+	p.level, p.caller = level, qualifiedName(f.pkg, fn)
+	defer func() {
+		p.level, p.caller = prevLevel, prevCaller
+	}()
+
+	// Cap the depth below functions of packages with their own depth
+	if pkgDepth, ok := p.packageDepth(f.pkg.PkgPath); ok && depth > pkgDepth-1 {
+		depth = pkgDepth - 1
+	}
+
 	if depth <= 0 {
-		return
+		return 0
 	}
 
 	// Check if function decleration has body
 	if fn.Body == nil {
-		return
+		return 0
 	}
 
+	// Collect the callees first, so only the first distinct new ones are followed
+	var callees []queuedFunction
+	prev := p.callees
+	if p.opts.MaxCalleesPerFunction > 0 {
+		p.callees = &callees
+	}
+
+	pkg := f.pkg
+	if p.opts.Concurrency > 1 {
+		p.prepareCallees(pkg.TypesInfo, fn.Body)
+	}
+	hints := p.implHints(pkg.Fset, f.file)
+	hinted := make(map[int]bool)
+	locals := localFuncs(pkg.TypesInfo, fn.Body)
+
 	// Inspect the AST of the function body
 	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		// Follow the functions wired into package level variables referenced by the function
+		if ident, ok := n.(*ast.Ident); ok && p.opts.FollowVarInitializers {
+			p.processVarFuncs(pkg.TypesInfo, ident, depth)
+			return true
+		}
+
 		// Check if the node is a call expression (function call)
 		ce, ok := n.(*ast.CallExpr)
 		if !ok {
 			return true
 		}
+
+		// Follow the implementations named by a hint comment attached to the line of the call
+		line := pkg.Fset.Position(ce.Pos()).Line
+		if typeNames, ok := hints[line]; ok {
+			matched := p.processImplHints(pkg.TypesInfo, ce, typeNames, depth)
+			hinted[line] = hinted[line] || matched
+		}
+
+		// Follow functions passed as arguments, e.g. handlers passed to an external router
+		for _, arg := range ce.Args {
+			p.processFunctionValue(pkg, arg, depth)
+		}
+
 		var funcNode *ast.Ident
 
-		// Get the function node from the call expression
-		switch fun := ce.Fun.(type) {
+		// Get the function node from the call expression, explicitly instantiated generic functions,
+		// like `Map[int](...)`, are called through an index expression
+		switch fun := uninstantiate(ce.Fun).(type) {
 		case *ast.Ident:
 			funcNode = fun
 		case *ast.SelectorExpr:
+			// Only the selected identifier is resolved, so the package qualifier may be an import alias.
+			// For promoted methods it resolves to the method of the embedded type declaring it,
+			// through any number of embedding levels, never to the embedded field itself. Methods
+			// promoted from an embedded interface resolve to the abstract method of the interface.
 			funcNode = fun.Sel
 		default:
+			// Calls of function literals, like `go func() { worker() }()`, are traversed
+			// into, so the calls in the literal body are followed like any other call.
+			// Calls in go and defer statements are regular call expressions.
 			return true
 		}
 
@@ -199,115 +1139,533 @@ func (p *parser) processUnderlyingFunctions(pkg *packages.Package, fn *ast.FuncD
 			return true
 		}
 
-		// Get the function signature from the function node
-		funcSig, ok := obj.Type().(*types.Signature)
+		// Method calls on a type parameter resolve to the abstract method of its constraint
+		if sel, ok := ce.Fun.(*ast.SelectorExpr); ok {
+			if selection, ok := pkg.TypesInfo.Selections[sel]; ok {
+				if tp, ok := selection.Recv().(*types.TypeParam); ok {
+					p.processConstraintMethod(tp, sel.Sel.Name, depth)
+					return true
+				}
+			}
+		}
+
+		// Calls of local variables follow the functions assigned to them in the function body, and calls of
+		// struct fields the functions assigned to the field with FollowFuncFields. Functions reaching the call in
+		// other ways, e.g. through a slice or map, are not followed, nor are the function literals of package
+		// level variables, like `var F = func() {...}`, called as `F()`.
+		if v, ok := obj.(*types.Var); ok {
+			for _, local := range locals[v] {
+				p.followFunction(local, depth)
+			}
+			if v.IsField() && p.opts.FollowFuncFields {
+				for _, fn := range p.fieldFuncs[v.Origin()] {
+					p.followFunction(fn, depth)
+				}
+			}
+			return true
+		}
+
+		// Get the called function from the function node. The methods of instantiated generic types are
+		// distinct objects, they are mapped back to the generic method, so it is included only once.
+		funcObj, ok := obj.(*types.Func)
 		if !ok {
 			return true
 		}
+		funcObj = funcObj.Origin()
+
+		// Load the source code of functions of the other dependencies from the module cache
+		if p.opts.IncludeModuleCacheSource {
+			if _, ok := p.funcToFileAndPkg[funcObj]; !ok {
+				if fn := p.moduleCacheFunction(funcObj); fn != nil {
+					funcObj = fn
+				}
+			}
+		}
+
+		// Record functions without loaded source code as external
+		if _, ok := p.funcToFileAndPkg[funcObj]; !ok {
+			p.processExternalFunction(funcObj)
+		}
 
 		// Process the underlying functions recursively
-		p.processFunction(funcSig, depth)
+		p.followFunction(funcObj, depth)
 
 		return true
 	})
+
+	p.callees = prev
+	warnUnmatchedHints(hints, hinted)
+
+	return p.followCallees(callees)
 }
 
-// extractSourceCode extracts the source code of a function, including comments, from the provided file and function declaration
-func extractSourceCode(fset *token.FileSet, file *ast.File, fn *ast.FuncDecl) (string, error) {
-	var sb strings.Builder
-	// Read the content of the file containing the function
-	fileContent, err := os.ReadFile(fset.Position(fn.Pos()).Filename)
-	if err != nil {
-		return "", err
+// warnUnmatchedHints warns about the hints attached to lines of calls, none of which is a call of a method of the
+// hinted types
+func warnUnmatchedHints(hints map[int][]string, hinted map[int]bool) {
+	lines := make([]int, 0, len(hinted))
+	for line, matched := range hinted {
+		if !matched {
+			lines = append(lines, line)
+		}
 	}
+	sort.Ints(lines)
 
-	// Split the file content into lines
-	lines := strings.Split(string(fileContent), "\n")
-	start := fset.Position(fn.Pos()).Line - 1
+	for _, line := range lines {
+		fmt.Println("Warning: scparser:impl hint matches no method call of", strings.Join(hints[line], ` `))
+	}
+}
 
-	// Include comments above the function
-	if fn.Doc != nil {
-		for _, comment := range fn.Doc.List {
-			if comment == nil {
+// followCallees follows the first MaxCalleesPerFunction distinct callees which are not yet processed,
+// in the order they were collected, and returns the number of omitted callees
+func (p *parser) followCallees(callees []queuedFunction) int {
+	followed := make(map[*types.Func]bool)
+	omitted := make(map[*types.Func]bool)
+	for _, callee := range callees {
+		if _, ok := p.funcToFileAndPkg[callee.obj]; !ok || followed[callee.obj] {
+			continue
+		}
+
+		// Processed functions reached with more depth left are expanded again without taking up a callee
+		if p.seen[callee.obj] {
+			if callee.depth > p.depths[callee.obj] {
+				p.followFunction(callee.obj, callee.depth)
+			}
+			continue
+		}
+
+		if len(followed) >= p.opts.MaxCalleesPerFunction {
+			omitted[callee.obj] = true
+			continue
+		}
+
+		followed[callee.obj] = true
+		p.followFunction(callee.obj, callee.depth)
+	}
+
+	return len(omitted)
+}
+
+// packageDepth returns the depth of the longest PackageDepths prefix matching the package path
+func (p *parser) packageDepth(pkgPath string) (int, bool) {
+	var match string
+	var found bool
+	for prefix := range p.opts.PackageDepths {
+		if (pkgPath == prefix || strings.HasPrefix(pkgPath, prefix+`/`)) && (!found || len(prefix) > len(match)) {
+			match = prefix
+			found = true
+		}
+	}
+
+	return p.opts.PackageDepths[match], found
+}
+
+// processFunctionValue processes the function referenced by the expression, if it refers to a declared function
+func (p *parser) processFunctionValue(pkg *packages.Package, expr ast.Expr, depth int) {
+	if fn := funcValue(pkg.TypesInfo, expr); fn != nil {
+		p.followFunction(fn, depth)
+	}
+}
+
+// funcValue returns the declared function or method the expression refers to, or nil if it refers to something else.
+// Method expressions, like `T.Method` or `(*T).Method`, and method values, like `v.Method` or `(&T{}).Method`,
+// refer to the method.
+func funcValue(info *types.Info, expr ast.Expr) *types.Func {
+	var ident *ast.Ident
+	switch expr := uninstantiate(expr).(type) {
+	case *ast.Ident:
+		ident = expr
+	case *ast.SelectorExpr:
+		ident = expr.Sel
+	default:
+		return nil
+	}
+
+	fn, _ := info.ObjectOf(ident).(*types.Func)
+
+	return fn
+}
+
+// implHintPrefix is the directive used to name the concrete implementation behind a dynamic call
+const implHintPrefix = `//scparser:impl `
+
+// implHints collects the scparser:impl hint comments in the file, keyed by the line of the calls they are attached
+// to. A hint is attached to the calls on the same line (trailing comment), or on the line directly below it if the
+// hint is on a line of its own. The hints are collected once per file.
+func (p *parser) implHints(fset *token.FileSet, file *ast.File) map[int][]string {
+	if hints, ok := p.fileHints[file]; ok {
+		return hints
+	}
+
+	hints := make(map[int][]string)
+	for _, cg := range file.Comments {
+		for _, c := range cg.List {
+			if !strings.HasPrefix(c.Text, implHintPrefix) {
 				continue
 			}
-			commentStart := fset.Position(comment.Pos()).Line - 1
-			commentEnd := fset.Position(comment.End()).Line - 1
-			for i := commentStart; i <= commentEnd; i++ {
-				sb.WriteString(lines[i])
-				sb.WriteString("\n")
+
+			typeNames := strings.Fields(strings.TrimPrefix(c.Text, implHintPrefix))
+			line := fset.Position(c.Pos()).Line
+			hints[line] = append(hints[line], typeNames...)
+			if p.ownLine(fset, c) {
+				hints[line+1] = append(hints[line+1], typeNames...)
 			}
 		}
 	}
+	p.fileHints[file] = hints
+
+	return hints
+}
 
-	// Extract the function source code from the start to end line
-	end := fset.Position(fn.End()).Line - 1
-	for i := start; i <= end; i++ {
-		sb.WriteString(lines[i])
-		sb.WriteString("\n")
+// ownLine reports whether only blanks precede the comment on its line
+func (p *parser) ownLine(fset *token.FileSet, c *ast.Comment) bool {
+	pos := fset.Position(c.Pos())
+	content, err := p.fileContent(pos.Filename)
+	if err != nil || pos.Offset > len(content) {
+		return false
 	}
 
-	return sb.String(), nil
+	lineStart := bytes.LastIndexByte(content[:pos.Offset], '\n') + 1
+	return len(bytes.TrimLeft(content[lineStart:pos.Offset], " \t")) == 0
 }
 
-// parseGoModFile parses the go.mod file and returns a slice of package paths.
-func parseGoModFile() []string {
-	content, err := os.ReadFile("go.mod")
-	if err != nil {
-		panic(err)
+// processImplHints follows the method of the hinted implementation types called by the call expression, and
+// reports whether it is a call of a method of one of the types. Calls of functions, including package qualified
+// ones like `pkg.Dispatch(x)`, and of methods the types don't have are left to the other calls of the line.
+func (p *parser) processImplHints(info *types.Info, ce *ast.CallExpr, typeNames []string, depth int) bool {
+	sel, ok := ce.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	if _, ok := info.Selections[sel]; !ok {
+		return false
 	}
 
-	var goModPaths []string
-	lines := strings.Split(string(content), "\n")
-	for _, line := range lines {
-		fields := strings.Fields(line)
-		if len(fields) > 0 {
-			pkg := fields[0]
-			if pkg == `module` || pkg == `require` {
-				pkg = fields[1]
-			}
-			if pkg != `` && pkg != `)` && pkg != `(` && pkg != `require` && pkg != `go` {
-				goModPaths = append(goModPaths, pkg)
+	var matched bool
+	for _, typeName := range typeNames {
+		named := p.lookupType(typeName)
+		if named == nil {
+			fmt.Println("Warning: scparser:impl type not found:", typeName)
+			continue
+		}
+
+		// Use the method set of the pointer type so both value and pointer receivers are included
+		mset := types.NewMethodSet(types.NewPointer(named))
+		for i := 0; i < mset.Len(); i++ {
+			if fn, ok := mset.At(i).Obj().(*types.Func); ok && fn.Name() == sel.Sel.Name {
+				matched = true
+				p.followFunction(fn, depth)
 			}
 		}
 	}
 
-	return goModPaths
+	return matched
+}
+
+// lookupType finds the named type referenced as pkg.Type, where pkg is either a package name or an import path
+func (m *ModuleParser) lookupType(typeName string) types.Type {
+	i := strings.LastIndex(typeName, `.`)
+	if i < 0 {
+		return nil
+	}
+	pkgName, name := typeName[:i], typeName[i+1:]
+
+	for _, pkg := range m.pkgs {
+		if pkg.Types == nil || (pkg.Name != pkgName && pkg.PkgPath != pkgName) {
+			continue
+		}
+
+		if obj, ok := pkg.Types.Scope().Lookup(name).(*types.TypeName); ok {
+			return obj.Type()
+		}
+	}
+
+	return nil
+}
+
+// extractFunction extracts the source code of a function, including its doc comments
+func (p *parser) extractFunction(fset *token.FileSet, fn *ast.FuncDecl) (string, error) {
+	return p.extractSourceCode(fset, fn, fn.Doc)
+}
+
+// extractSignature extracts the doc comments and signature of a function, omitting its body
+func (p *parser) extractSignature(fset *token.FileSet, fn *ast.FuncDecl) (string, error) {
+	if fn.Body == nil {
+		return p.extractSourceCode(fset, fn, fn.Doc)
+	}
+
+	src, err := p.extractSourceRange(fset, fn.Pos(), fn.Body.Lbrace, fn.Doc)
+	if err != nil {
+		return "", err
+	}
+
+	// The range ends before the opening brace of the body
+	return strings.TrimRight(src, " \t\n") + "\n", nil
 }
 
-// loadPackages loads and returns the (sub)packages in the current working directory.
-func loadPackages() []*packages.Package {
-	err := exec.Command(`go`, `mod`, `vendor`).Run()
+// extractSourceCode extracts the source code of a declaration, including its doc comments, from the file containing it
+func (p *parser) extractSourceCode(fset *token.FileSet, fn ast.Node, doc *ast.CommentGroup) (string, error) {
+	return p.extractSourceRange(fset, fn.Pos(), fn.End(), doc)
+}
+
+// extractSourceRange extracts the byte range from start to end, preceded by the doc comments, from the file containing it.
+// The range is widened to the start of its first line if only blanks precede it, and to the end of its last line
+// if only blanks or a line comment follow it, keeping the indentation and trailing comments, but not the code of
+// other declarations sharing the lines. CRLF line endings are normalized to LF.
+func (p *parser) extractSourceRange(fset *token.FileSet, startPos, endPos token.Pos, doc *ast.CommentGroup) (string, error) {
+	// Include comments above the function
+	if doc != nil && doc.Pos() < startPos {
+		startPos = doc.Pos()
+	}
+
+	filename := fset.Position(startPos).Filename
+	content, err := p.fileContent(filename)
+	if err != nil {
+		return "", err
+	}
+
+	start := fset.Position(startPos).Offset
+	end := fset.Position(endPos).Offset
+	if start < 0 || end > len(content) || start > end {
+		return "", fmt.Errorf("range %d-%d out of bounds of %s", start, end, filename)
+	}
+
+	if lineStart := bytes.LastIndexByte(content[:start], '\n') + 1; len(bytes.TrimLeft(content[lineStart:start], " \t")) == 0 {
+		start = lineStart
+	}
+
+	lineEnd := len(content)
+	if i := bytes.IndexByte(content[end:], '\n'); i >= 0 {
+		lineEnd = end + i
+	}
+	if rest := bytes.TrimLeft(content[end:lineEnd], " \t\r"); len(rest) == 0 || bytes.HasPrefix(rest, []byte(`//`)) {
+		end = lineEnd
+	}
+
+	// Normalize CRLF line endings, so files saved on Windows do not leave a carriage return on every line
+	src := strings.ReplaceAll(string(content[start:end]), "\r\n", "\n")
+	return strings.TrimSuffix(src, "\r") + "\n", nil
+}
+
+// fileContent returns the content the file was parsed from when loading the packages, so the positions of the syntax
+// tree match it, reading it the first time it is needed if it was not parsed
+func (p *parser) fileContent(filename string) ([]byte, error) {
+	if content, ok := p.sources.content(filename); ok {
+		return content, nil
+	}
+	p.readMu.Lock()
+	defer p.readMu.Unlock()
+	if content, ok := p.read[filename]; ok {
+		return content, nil
+	}
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	p.read[filename] = content
+
+	return content, nil
+}
+
+// parseGoModFile parses the go.mod file in the directory and returns a slice of package paths starting with the module path,
+// and the required version of each required module.
+func parseGoModFile(dir string) ([]string, map[string]string, error) {
+	content, err := os.ReadFile(filepath.Join(dir, `go.mod`))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrGoModNotFound, err)
+	}
+
+	file, err := modfile.Parse(`go.mod`, content, nil)
 	if err != nil {
-		fmt.Println("Warning: go mod vendor failed:", err)
+		return nil, nil, fmt.Errorf("%w: %w", ErrGoModNotFound, err)
+	}
+	if file.Module == nil || file.Module.Mod.Path == `` {
+		return nil, nil, fmt.Errorf("%w: module directive not found in go.mod", ErrGoModNotFound)
+	}
+
+	// The module path is always the first path, followed by the required modules
+	goModPaths := []string{file.Module.Mod.Path}
+	versions := make(map[string]string)
+	for _, req := range file.Require {
+		goModPaths = append(goModPaths, req.Mod.Path)
+		versions[req.Mod.Path] = req.Mod.Version
 	}
-	pkgs, err := packages.Load(&packages.Config{
-		Mode: packages.NeedName | packages.NeedFiles | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo,
-	}, "...")
+
+	return goModPaths, versions, nil
+}
+
+// ParserMode is the mode the source files are parsed with. Comments are always parsed,
+// so doc comments are associated with their declarations and directive comments are found.
+const ParserMode = goparser.AllErrors | goparser.ParseComments
+
+// loadMode is the mode the packages are loaded with
+const loadMode = packages.NeedName | packages.NeedFiles | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo
+
+// parseFile parses the source files of the loaded packages with ParserMode
+func parseFile(fset *token.FileSet, filename string, src []byte) (*ast.File, error) {
+	return goparser.ParseFile(fset, filename, src, ParserMode)
+}
+
+// loadConfig returns the configuration to load the packages in the directory with,
+// for the BuildTags and the target GOOS and GOARCH of the options
+func loadConfig(dir string, opts Options) *packages.Config {
+	cfg := &packages.Config{
+		Mode:      loadMode,
+		Dir:       dir,
+		ParseFile: parseFile,
+		Overlay:   opts.Overlay,
+	}
+
+	if len(opts.BuildTags) > 0 {
+		cfg.BuildFlags = []string{`-tags=` + strings.Join(opts.BuildTags, `,`)}
+	}
+
+	if opts.GOOS != `` || opts.GOARCH != `` {
+		cfg.Env = os.Environ()
+		if opts.GOOS != `` {
+			cfg.Env = append(cfg.Env, `GOOS=`+opts.GOOS)
+		}
+		if opts.GOARCH != `` {
+			cfg.Env = append(cfg.Env, `GOARCH=`+opts.GOARCH)
+		}
+	}
+
+	return cfg
+}
+
+// loadPackages loads and returns the packages matching the patterns, or all (sub)packages in the directory of the
+// configuration if there are none. With vendor, `go mod vendor` is run first if the vendor directory is stale.
+// With tests, the packages of the module are replaced by their variants including the test files.
+func loadPackages(cfg *packages.Config, vendor, tests bool, patterns ...string) ([]*packages.Package, error) {
+	if vendor && vendorStale(cfg.Dir) {
+		cmd := exec.Command(`go`, `mod`, `vendor`)
+		cmd.Dir = cfg.Dir
+		if err := cmd.Run(); err != nil {
+			fmt.Println("Warning: go mod vendor failed:", err)
+		}
+	}
+	if len(patterns) == 0 {
+		patterns = []string{"..."}
+	}
+	pkgs, err := packages.Load(cfg, patterns...)
 	if err != nil {
-		panic(err)
+		// Report a canceled load with the error of the context, go list only reports it as text
+		if cfg.Context != nil && cfg.Context.Err() != nil {
+			err = cfg.Context.Err()
+		}
+		return nil, fmt.Errorf("%w: %w", ErrPackageLoad, err)
 	}
 	if len(pkgs) == 0 {
-		panic(`no packages found`)
+		return nil, fmt.Errorf("%w: no packages found", ErrPackageLoad)
+	}
+	if tests {
+		return withTestVariants(cfg, pkgs)
+	}
+	return pkgs, nil
+}
+
+// withTestVariants replaces the packages of the module in the directory by their variants including the internal
+// test files, like `p [p.test]`, which have the import path of the package itself. The external test packages are
+// added after them. Only the module itself is loaded with tests, as loading the tests of the dependencies is slow.
+func withTestVariants(cfg *packages.Config, pkgs []*packages.Package) ([]*packages.Package, error) {
+	testCfg := *cfg
+	testCfg.Tests = true
+	testPkgs, err := packages.Load(&testCfg, "./...")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrPackageLoad, err)
+	}
+
+	variants := make(map[string]*packages.Package)
+	var external []*packages.Package
+	for _, pkg := range testPkgs {
+		switch {
+		case strings.HasSuffix(pkg.PkgPath, `.test`):
+			// Skip the generated test main packages
+		case strings.HasSuffix(pkg.PkgPath, `_test`):
+			external = append(external, pkg)
+		case pkg.ID != pkg.PkgPath:
+			variants[pkg.PkgPath] = pkg
+		}
+	}
+
+	result := make([]*packages.Package, 0, len(pkgs)+len(external))
+	for _, pkg := range pkgs {
+		if variant, ok := variants[pkg.PkgPath]; ok {
+			pkg = variant
+		}
+		result = append(result, pkg)
+	}
+
+	return append(result, external...), nil
+}
+
+// vendorStale reports whether the vendor directory of the module in the directory is missing,
+// or older than its go.mod or go.sum file
+func vendorStale(dir string) bool {
+	vendored, err := os.Stat(filepath.Join(dir, `vendor`, `modules.txt`))
+	if err != nil {
+		return true
+	}
+
+	for _, name := range []string{`go.mod`, `go.sum`} {
+		if info, err := os.Stat(filepath.Join(dir, name)); err == nil && info.ModTime().After(vendored.ModTime()) {
+			return true
+		}
 	}
-	return pkgs
+
+	return false
 }
 
-// initialize searches for the target function with the provided name in the root package loads the go.mod packages
-func initialize(funcName string) (*types.Signature, map[*types.Signature]fileAndPkg) {
-	goModPaths := parseGoModFile()
-	pkgs := loadPackages()
+// initialize loads the go.mod packages, or only those matching the patterns if given, and collects the file
+// and package, and the directives, of every function declared in them
+func initialize(ctx context.Context, dir string, opts Options, patterns ...string) (*ModuleParser, error) {
+	goModPaths, versions, err := parseGoModFile(dir)
+	if err != nil {
+		return nil, err
+	}
+	cfg := loadConfig(dir, opts)
+	cfg.Context = ctx
+	sources := newFileSources()
+	cfg.ParseFile = sources.parseFile
+	pkgs, err := loadPackages(cfg, opts.Vendor, opts.Tests, patterns...)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &ModuleParser{
+		dir:              dir,
+		config:           cfg,
+		sources:          sources,
+		tests:            opts.Tests,
+		goModPaths:       goModPaths,
+		versions:         versions,
+		pkgByPath:        make(map[string]*packages.Package),
+		funcToFileAndPkg: make(map[*types.Func]fileAndPkg),
+		varFuncs:         make(map[*types.Var][]*types.Func),
+		fieldFuncs:       make(map[*types.Var][]*types.Func),
+		varFuncLits:      make(map[*types.Func]*ast.FuncDecl),
+		cachePkgs:        make(map[string]*packages.Package),
+	}
 
-	// Return the signature of the intial target function
-	var funcSig *types.Signature
+	// Order the packages by import path instead of the dependency order go list reports them in, so the order
+	// in which the packages are searched, e.g. for the implementations of an interface, and the order of the
+	// collected directives do not change with the imports between them
+	sort.SliceStable(pkgs, func(i, j int) bool {
+		return pkgs[i].PkgPath < pkgs[j].PkgPath
+	})
 
-	// Collect all function signatures and their respective files
-	funcToFileAndPkg := make(map[*types.Signature]fileAndPkg)
+	// Collect all function objects and their respective files
 	for _, pkg := range pkgs {
-		// Skip packages not listed in go.mod
-		if !isGoModPkg(goModPaths, pkg.PkgPath) {
+		// Skip packages not listed in go.mod, the external test packages belong to the package they test
+		pkgPath := pkg.PkgPath
+		if opts.Tests {
+			pkgPath = strings.TrimSuffix(pkgPath, `_test`)
+		}
+		if !isGoModPkg(goModPaths, pkgPath) {
 			continue
 		}
+		m.pkgs = append(m.pkgs, pkg)
+		m.pkgByPath[pkg.PkgPath] = pkg
 
 		for _, file := range pkg.Syntax {
 			ast.Inspect(file, func(n ast.Node) bool {
@@ -317,43 +1675,54 @@ func initialize(funcName string) (*types.Signature, map[*types.Signature]fileAnd
 					return true
 				}
 
-				// Get the function signature from the TypesInfo of the package
-				obj := pkg.TypesInfo.ObjectOf(fn.Name)
-				if obj == nil {
-					return true
-				}
-
-				sig, ok := obj.Type().(*types.Signature)
-				if !ok || sig == nil {
+				// Get the function object from the TypesInfo of the package
+				obj, ok := pkg.TypesInfo.ObjectOf(fn.Name).(*types.Func)
+				if !ok {
 					return true
 				}
 
-				funcToFileAndPkg[sig] = fileAndPkg{
-					file: file,
-					pkg:  pkg,
+				m.funcToFileAndPkg[obj] = fileAndPkg{
+					file:     file,
+					pkg:      pkg,
+					exported: fn.Name.IsExported(),
 				}
 
-				// If the function is the initial target function, store the signature
-				if pkg.PkgPath == goModPaths[0] && fn.Name.Name == funcName {
-					funcSig = sig
+				// Collect the scparser directives in the doc comments of the function
+				for _, d := range parseDirectives(fn.Doc) {
+					d.obj = obj
+					m.directives = append(m.directives, d)
 				}
 
 				return true
 			})
-		}
 
-		if pkg.PkgPath == goModPaths[0] && funcSig == nil {
-			panic(fmt.Sprintf("Function %s not found in package path", funcName))
+			// Collect the function values in the composite literals initializing package level variables, and the
+			// variables initialized with a function literal
+			for _, decl := range file.Decls {
+				if gd, ok := decl.(*ast.GenDecl); ok && gd.Tok == token.VAR {
+					m.collectVarFuncs(pkg.TypesInfo, gd)
+					m.collectVarFuncLits(pkg, file, gd)
+				}
+			}
+
+			// Collect the functions assigned to struct fields of function type
+			m.collectFieldFuncs(pkg.TypesInfo, file)
 		}
 	}
 
-	return funcSig, funcToFileAndPkg
+	return m, nil
 }
 
 // isGoModPkg checks if the provided package path is listed in the go.mod file
 func isGoModPkg(goModPaths []string, pkgPath string) bool {
-	// Iterate through the goModPaths to check if the given package path matches or is a subpackage of any listed package
-	for _, path := range goModPaths {
+	return hasPathPrefix(goModPaths, pkgPath)
+}
+
+// hasPathPrefix reports whether the package path matches or is a subpackage of any of the given package paths.
+// A trailing `/...` of a path is ignored, so `github.com/x/y/...` matches github.com/x/y and its subpackages.
+func hasPathPrefix(paths []string, pkgPath string) bool {
+	for _, path := range paths {
+		path = strings.TrimSuffix(path, `/...`)
 		if pkgPath == path || strings.HasPrefix(pkgPath, path+`/`) {
 			return true
 		}
@@ -361,3 +1730,8 @@ func isGoModPkg(goModPaths []string, pkgPath string) bool {
 
 	return false
 }
+
+// withinPrefixes reports whether the functions of the package are followed according to IncludePrefixes
+func (p *parser) withinPrefixes(pkgPath string) bool {
+	return len(p.opts.IncludePrefixes) == 0 || hasPathPrefix(p.opts.IncludePrefixes, pkgPath)
+}
@@ -0,0 +1,455 @@
+package scparser
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testModDir is the directory of the module the tests parse
+var testModDir = filepath.Join(`testdata`, `mod`)
+
+var (
+	testModOnce sync.Once
+	testMod     *ModuleParser
+)
+
+// testModule returns the parser of the test module, which is loaded once for all tests
+func testModule(t *testing.T) *ModuleParser {
+	t.Helper()
+
+	testModOnce.Do(func() {
+		testMod = NewModuleParser(testModDir)
+	})
+
+	return testMod
+}
+
+// parseTest parses the function of the root package of the test module with the options
+func parseTest(t *testing.T, funcName string, opts Options) string {
+	t.Helper()
+
+	return testModule(t).ParseWithOptions(funcName, opts)
+}
+
+// assertContains fails the test if the output does not contain each of the substrings
+func assertContains(t *testing.T, output string, substrs ...string) {
+	t.Helper()
+
+	for _, s := range substrs {
+		if !strings.Contains(output, s) {
+			t.Errorf("output does not contain %q:\n%s", s, output)
+		}
+	}
+}
+
+// assertNotContains fails the test if the output contains one of the substrings
+func assertNotContains(t *testing.T, output string, substrs ...string) {
+	t.Helper()
+
+	for _, s := range substrs {
+		if strings.Contains(output, s) {
+			t.Errorf("output contains %q:\n%s", s, output)
+		}
+	}
+}
+
+func TestDocCommentsAreParsed(t *testing.T) {
+	decls := testModule(t).FuncDecls(`DocRoot`, false)
+	if len(decls) == 0 {
+		t.Fatal(`no declarations`)
+	}
+
+	for _, decl := range decls {
+		if decl.Decl.Doc == nil {
+			t.Fatalf("%s has no doc comment", decl.Decl.Name.Name)
+		}
+	}
+	if doc := decls[0].Decl.Doc.Text(); doc != "DocRoot calls a documented helper.\n" {
+		t.Errorf("unexpected doc comment %q", doc)
+	}
+}
+
+func TestSameSignatureInDifferentPackages(t *testing.T) {
+	output := parseTest(t, `SignatureRoot`, Options{})
+
+	assertContains(t, output, "alpha\n```go\n// Sum adds the numbers.", "beta\n```go\n// Sum subtracts the numbers.", `return a + b`, `return a - b`)
+}
+
+func TestFenceLanguage(t *testing.T) {
+	lines := strings.Split(strings.TrimSpace(parseTest(t, `DescribeRoot`, Options{})), "\n")
+	if lines[0] != "```go" {
+		t.Errorf("opening fence is %q", lines[0])
+	}
+	if last := lines[len(lines)-1]; last != "```" {
+		t.Errorf("closing fence is %q", last)
+	}
+
+	lines = strings.Split(parseTest(t, `DescribeRoot`, Options{FenceLanguage: `golang`}), "\n")
+	if lines[0] != "```golang" {
+		t.Errorf("opening fence with FenceLanguage is %q", lines[0])
+	}
+}
+
+func TestMethodExpressionsAndValues(t *testing.T) {
+	output := parseTest(t, `MethodValueRoot`, Options{})
+
+	assertContains(t, output, `func (c Counter) Get() int {`, `func (c *Counter) Inc() {`)
+}
+
+func TestGenericInstantiationsIncludedOnce(t *testing.T) {
+	output := parseTest(t, `MapRoot`, Options{})
+
+	if n := strings.Count(output, `func Map[T, U any](s []T, f func(T) U) []U {`); n != 1 {
+		t.Errorf("generic function included %d times:\n%s", n, output)
+	}
+}
+
+func TestCyclesDoNotShortenChains(t *testing.T) {
+	output := parseTest(t, `CycleRoot`, Options{MaxDepth: 4})
+
+	for _, name := range []string{`func ping(`, `func pong(`} {
+		if n := strings.Count(output, name); n != 1 {
+			t.Errorf("%s included %d times", name, n)
+		}
+	}
+	assertContains(t, output, `func chain1()`, `func chain2()`, `func chain3()`)
+	assertNotContains(t, output, `func chain4()`)
+}
+
+func TestShorterPathsExpandAgain(t *testing.T) {
+	output := parseTest(t, `ShortcutRoot`, Options{MaxDepth: 4})
+
+	if n := strings.Count(output, `func shortTarget()`); n != 1 {
+		t.Errorf("shortTarget included %d times", n)
+	}
+	assertContains(t, output, `func longB()`, `func shortBelow()`, `func shortBottom()`)
+
+	output = parseTest(t, `ShortcutRoot`, Options{MaxDepth: 3})
+
+	assertContains(t, output, `func shortBelow()`)
+	assertNotContains(t, output, `func shortBottom()`)
+}
+
+func TestDeclarationsSharingLines(t *testing.T) {
+	output := parseTest(t, `SharedLineRoot`, Options{})
+
+	assertContains(t, output, "```go\nfunc SharedLineRoot() { _ = sharedVar; sharedHelper() }\n", "\nfunc sharedHelper() {}\n```")
+	assertNotContains(t, output, `var sharedVar`, `sharedAfter`)
+}
+
+func TestCRLFLineEndings(t *testing.T) {
+	dir, err := filepath.Abs(testModDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := "package mod\r\n\r\n// CRLFRoot is saved with CRLF line endings.\r\nfunc CRLFRoot() {\r\n\tcrlfHelper() // trailing\r\n}\r\n\r\nfunc crlfHelper() {}\r\n"
+	opts := Options{Overlay: map[string][]byte{filepath.Join(dir, `crlf.go`): []byte(src)}}
+	m, err := newModuleParser(testModDir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := m.ParseWithOptionsE(`CRLFRoot`, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertNotContains(t, output, "\r")
+	assertContains(t, output, "// CRLFRoot is saved with CRLF line endings.\nfunc CRLFRoot() {\n\tcrlfHelper() // trailing\n}\n", "\nfunc crlfHelper() {}\n")
+}
+
+func TestAliasedImportCalls(t *testing.T) {
+	output := parseTest(t, `AliasRoot`, Options{})
+
+	assertContains(t, output, "\treturn al.Sum(1, 2)\n", "alpha\n```go\n// Sum adds the numbers.\nfunc Sum(a, b int) int {")
+}
+
+func TestPromotedMethodThreeLevels(t *testing.T) {
+	output := parseTest(t, `EmbedRoot`, Options{})
+
+	assertContains(t, output, "// Hello is promoted through the embedding types.\nfunc (Inner) Hello() string { return \"hello\" }\n")
+}
+
+func TestPromotedMethodTwoLevels(t *testing.T) {
+	output := parseTest(t, `EmbedTwoRoot`, Options{})
+
+	assertContains(t, output, "// Greet is promoted through two levels of embedding.\nfunc (*Greeter) Greet() string { return \"hi\" }\n")
+	assertNotContains(t, output, `type Host struct`)
+}
+
+func TestGoroutineEntryFunctions(t *testing.T) {
+	output := parseTest(t, `PoolRoot`, Options{})
+
+	assertContains(t, output, `func closureWorker() {}`, `func directWorker() {}`)
+}
+
+func TestCallsInsideFunctionLiterals(t *testing.T) {
+	output := parseTest(t, `ClosureRoot`, Options{})
+
+	assertContains(t, output, `func assignedHelper() {}`, `func deferredHelper() {}`, `func nestedHelper() {}`)
+}
+
+func TestPackageBlocksAreContiguous(t *testing.T) {
+	output := parseTest(t, `PackagesRoot`, Options{ResolveInterfaces: true})
+
+	start := strings.Index(output, `func Start() {`)
+	save := strings.Index(output, `func (saver) Save() {}`)
+	mid := strings.Index(output, `func Mid(s Saver) {`)
+	if start < 0 || save < 0 || mid < 0 {
+		t.Fatalf("missing functions:\n%s", output)
+	}
+	if !(start < save && save < mid) {
+		t.Errorf("the functions of alpha are not together before beta:\n%s", output)
+	}
+	if n := strings.Count(output, "\nalpha\n"); n != 1 {
+		t.Errorf("alpha appears %d times:\n%s", n, output)
+	}
+}
+
+func TestUniverseMethodValues(t *testing.T) {
+	output := parseTest(t, `ErrValueRoot`, Options{IncludePrefixes: []string{`example.com/mod`}})
+
+	assertContains(t, output, `func applyErr(f func() string) string { return f() }`)
+}
+
+func TestImplHints(t *testing.T) {
+	src := parseTest(t, `HintRoot`, Options{})
+
+	assertContains(t, src, `func (m *Memory) Save(v string)`, `func audit() {}`, `func keep(f func()) {}`)
+	assertNotContains(t, src, `func (m *Memory) Load()`, `func (m *Memory) Wipe()`, `func (d *Disk) Load()`)
+}
+
+func TestDedupBySource(t *testing.T) {
+	output := parseTest(t, `DedupRoot`, Options{DedupBySource: true})
+
+	assertContains(t, output, "func sameA(x int) int { return x + 1 }\n// also: example.com/mod.sameB\n", `func sameWide(x int64) int64 { return x + 1 }`)
+	assertNotContains(t, output, `func sameB(`)
+
+	output = testModule(t).ParseMany([]string{`DupOne`, `DupTwo`}, Options{DedupBySource: true})
+
+	assertContains(t, output, `func DupOne() { sameA(1) }`, `func DupTwo() { sameA(1) }`)
+	assertNotContains(t, output, `// also:`)
+}
+
+func TestResumeCountsOnlyTheStep(t *testing.T) {
+	output, r, err := testModule(t).ParseIncremental(`ShortcutRoot`, Options{MaxBytes: 1}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := 1
+	for r != nil {
+		var next string
+		next, r = Resume(r, 10)
+		if len(next) <= len(output) {
+			t.Fatalf("step %d added no functions:\n%s", steps+1, next)
+		}
+		output = next
+		steps++
+	}
+
+	if steps != 6 {
+		t.Errorf("expected 6 steps of one function, got %d:\n%s", steps, output)
+	}
+	assertContains(t, output, `func longB() { shortTarget() }`, `func shortBottom() {}`)
+	assertNotContains(t, output, `Truncated`)
+}
+
+func TestDirectivesInPackagePathOrder(t *testing.T) {
+	output, err := testModule(t).ParseGroup(`order`, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mod, alpha := strings.Index(output, `func GroupMod() {}`), strings.Index(output, `func GroupAlpha() {}`)
+	if mod < 0 || alpha < 0 || mod > alpha {
+		t.Errorf("expected GroupMod before GroupAlpha:\n%s", output)
+	}
+}
+
+func TestParseJSONFields(t *testing.T) {
+	data, err := testModule(t).ParseJSON(`DocRoot`, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pkgs []map[string]any
+	if err := json.Unmarshal(data, &pkgs); err != nil {
+		t.Fatal(err)
+	}
+	if len(pkgs) != 1 || pkgs[0][`name`] != `mod` || pkgs[0][`pkgPath`] != `example.com/mod` {
+		t.Fatalf("unexpected packages:\n%s", data)
+	}
+
+	functions := pkgs[0][`functions`].([]any)
+	if len(functions) != 2 {
+		t.Fatalf("expected 2 functions:\n%s", data)
+	}
+	root := functions[0].(map[string]any)
+	for _, key := range []string{`name`, `pkgPath`, `file`, `line`, `endLine`, `start`, `end`, `depth`, `source`} {
+		if _, ok := root[key]; !ok {
+			t.Errorf("function has no %s field:\n%s", key, data)
+		}
+	}
+
+	want := map[string]any{
+		`name`:    `example.com/mod.DocRoot`,
+		`pkgPath`: `example.com/mod`,
+		`line`:    float64(4),
+		`endLine`: float64(6),
+		`depth`:   float64(1),
+		`source`:  "// DocRoot calls a documented helper.\nfunc DocRoot() {\n\tdocHelper()\n}\n",
+	}
+	for key, value := range want {
+		if root[key] != value {
+			t.Errorf("expected %s %v, got %v", key, value, root[key])
+		}
+	}
+	if file := root[`file`].(string); filepath.Base(file) != `doc.go` {
+		t.Errorf("unexpected file %s", file)
+	}
+	start, end := root[`start`].(map[string]any), root[`end`].(map[string]any)
+	if start[`Line`] != float64(4) || start[`Column`] != float64(1) || end[`Line`] != float64(6) || end[`Column`] != float64(2) {
+		t.Errorf("unexpected positions %v and %v", start, end)
+	}
+	if depth := functions[1].(map[string]any)[`depth`]; depth != float64(2) {
+		t.Errorf("expected depth 2 of docHelper, got %v", depth)
+	}
+}
+
+func TestMaxBytesBreadthFirst(t *testing.T) {
+	output := parseTest(t, `BudgetRoot`, Options{MaxBytes: 250})
+
+	assertContains(t, output, `func budgetDeep() { budgetDeeper() }`, `func budgetNear() { budgetTiny() }`, `Truncated: output exceeds the maximum of 250 bytes`)
+	assertNotContains(t, output, `func budgetDeeper() {`)
+}
+
+func TestMaxBytesStopsAtFirstMiss(t *testing.T) {
+	output := parseTest(t, `BudgetRoot`, Options{MaxBytes: 250})
+
+	// budgetTiny still fits in the limit, but is processed after budgetDeeper, which does not
+	assertNotContains(t, output, `func budgetTiny() {}`)
+	if !strings.HasSuffix(output, "```\n\nTruncated: output exceeds the maximum of 250 bytes") {
+		t.Errorf("output does not end with the truncation note:\n%s", output)
+	}
+}
+
+func TestMaxBytesExportedFirst(t *testing.T) {
+	output := parseTest(t, `ExportedFirstRoot`, Options{MaxBytes: 200})
+
+	assertContains(t, output, `func SecondCalled() {}`)
+	assertNotContains(t, output, `func firstCalled() {}`)
+
+	// Without a limit the functions are in the order they are called
+	output = parseTest(t, `ExportedFirstRoot`, Options{})
+	if strings.Index(output, `func firstCalled() {}`) > strings.Index(output, `func SecondCalled() {}`) {
+		t.Errorf("expected firstCalled before SecondCalled:\n%s", output)
+	}
+}
+
+func TestParseStructured(t *testing.T) {
+	r, err := testModule(t).ParseStructured(`PackagesRoot`, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, pkg := range r.Packages {
+		got = append(got, pkg.Name+` `+pkg.PkgPath)
+		for _, fn := range pkg.Functions {
+			got = append(got, "\t"+fn.Name)
+			if fn.PkgPath != pkg.PkgPath || filepath.Dir(fn.File) == `` || fn.Line <= 0 || fn.EndLine < fn.Line || fn.Source == `` {
+				t.Errorf("unexpected function %+v", fn)
+			}
+		}
+	}
+	want := []string{`mod example.com/mod`, "\texample.com/mod.PackagesRoot", `alpha example.com/mod/alpha`, "\texample.com/mod/alpha.Start", `beta example.com/mod/beta`, "\texample.com/mod/beta.Mid"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected packages:\n%s", strings.Join(got, "\n"))
+	}
+	if root := r.Packages[0].Functions[0]; filepath.Base(root.File) != `packages.go` || root.Line != 6 || root.EndLine != 8 || root.Depth != 1 {
+		t.Errorf("unexpected position of the root %+v", root)
+	}
+
+	if output := parseTest(t, `PackagesRoot`, Options{}); r.String() != output {
+		t.Errorf("String differs from the output of Parse:\n%s\n\n%s", r.String(), output)
+	}
+	if stats := r.Stats(); stats.Functions != 3 || stats.Packages != 3 || stats.Lines != 12 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	// A depth of 0 only includes the root function
+	r, err = ParseStructured(testModDir, `PackagesRoot`, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Packages) != 1 || len(r.Packages[0].Functions) != 1 {
+		t.Errorf("expected only the root function, got %+v", r.Packages)
+	}
+}
+
+func TestParseStreamOrder(t *testing.T) {
+	var sb strings.Builder
+	if err := testModule(t).ParseStream(&sb, `StreamRoot`, Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The functions are written in the order they are processed, opening a new block when the package changes
+	want := "```go\n// StreamRoot calls into alpha and then back into its own package.\nfunc StreamRoot() int {\n\treturn alpha.Sum(1, 2) + streamHelper()\n}\n```\n\n" +
+		"alpha\n```go\n// Sum adds the numbers.\nfunc Sum(a, b int) int {\n\treturn a + b\n}\n```\n\n" +
+		"mod\n```go\nfunc streamHelper() int { return 3 }\n```"
+	if sb.String() != want {
+		t.Errorf("unexpected output:\n%s", sb.String())
+	}
+}
+
+func TestSkipGeneratedWrappers(t *testing.T) {
+	output := parseTest(t, `WrapperRoot`, Options{SkipGeneratedWrappers: true, MaxDepth: 3})
+
+	// The wrapper does not use up a level of depth, so realHelper is still included
+	assertContains(t, output, `generated wrapper of (*example.com/mod.realImpl).Do elided`, `func (r *realImpl) Do() int { return realHelper() }`, `func realHelper() int { return 1 }`)
+	assertNotContains(t, output, `func (w *wrapped) Do() int`)
+
+	output = parseTest(t, `WrapperRoot`, Options{MaxDepth: 3})
+
+	assertContains(t, output, `func (w *wrapped) Do() int { return w.inner.Do() }`, `func (r *realImpl) Do() int { return realHelper() }`)
+	assertNotContains(t, output, `elided`, `func realHelper() int`)
+}
+
+func TestFollowVarInitializers(t *testing.T) {
+	output := parseTest(t, `WiringRoot`, Options{FollowVarInitializers: true})
+
+	assertContains(t, output, `func (r *Repo) Save(v string) {}`, "func loadDefault() string { return `` }")
+
+	output = parseTest(t, `WiringRoot`, Options{})
+
+	assertNotContains(t, output, `func (r *Repo) Save(`, `func loadDefault()`)
+}
+
+func TestFollowFuncFields(t *testing.T) {
+	output := parseTest(t, `FieldRoot`, Options{FollowFuncFields: true})
+
+	assertContains(t, output, `func serve() {}`, `func reset() {}`)
+	assertNotContains(t, output, `func NewHandler()`, `func (h *Handler) Reset()`)
+
+	output = parseTest(t, `FieldRoot`, Options{})
+
+	assertNotContains(t, output, `func serve()`, `func reset()`)
+}
+
+func TestMaxCalleesPerFunction(t *testing.T) {
+	output := parseTest(t, `FanRoot`, Options{MaxCalleesPerFunction: 2})
+
+	// The callees are counted once, in the order they are called
+	assertContains(t, output, "\tfanD()\n}\n// (2 more callees omitted)\n", `func fanA() {}`, `func fanB() {}`)
+	assertNotContains(t, output, `func fanC() {}`, `func fanD() {}`)
+
+	output = parseTest(t, `FanRoot`, Options{MaxCalleesPerFunction: 4})
+
+	assertContains(t, output, `func fanD() {}`)
+	assertNotContains(t, output, `more callees omitted`)
+}
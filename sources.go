@@ -0,0 +1,37 @@
+package scparser
+
+import (
+	"go/ast"
+	"go/token"
+	"sync"
+)
+
+// fileSources holds the content of the source files as they were parsed when loading the packages, so the
+// declarations are extracted from the same content their syntax trees and positions were built from, even if
+// the files changed on disk since
+type fileSources struct {
+	mu       sync.Mutex
+	contents map[string][]byte
+}
+
+func newFileSources() *fileSources {
+	return &fileSources{contents: make(map[string][]byte)}
+}
+
+// parseFile parses the source file with ParserMode, keeping its content. The packages are parsed concurrently.
+func (s *fileSources) parseFile(fset *token.FileSet, filename string, src []byte) (*ast.File, error) {
+	s.mu.Lock()
+	s.contents[filename] = src
+	s.mu.Unlock()
+
+	return parseFile(fset, filename, src)
+}
+
+// content returns the content the file was parsed from, if it was parsed
+func (s *fileSources) content(filename string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	content, ok := s.contents[filename]
+	return content, ok
+}
@@ -0,0 +1,120 @@
+package scparser
+
+import (
+	"io"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// ParseStream writes the source code of the specified function and its underlying functions to w as they are
+// processed, instead of building the output in memory. See (*ModuleParser).ParseStream.
+func ParseStream(w io.Writer, funcPkgPath, funcName string, opts Options) error {
+	m, err := newModuleParser(funcPkgPath, opts)
+	if err != nil {
+		return err
+	}
+
+	return m.ParseStream(w, funcName, opts)
+}
+
+// ParseStream writes the source code of the specified function and its underlying functions to w as they are
+// processed, keeping only the set of processed functions in memory. As the output can not be reordered, a new
+// block with a package header starts whenever the package changes, so packages may appear more than once.
+// ExcludeRoot leaves out the functions of the root package. The options that need all functions at once, such
+// as Order, GroupByReceiver, InterfaceOrder, MaxFilesPerPackage, IncludeImports, AnnotateDepth, IncludeTests,
+// IncludePreamble and the note of the callees omitted by MaxCalleesPerFunction, have no effect.
+func (m *ModuleParser) ParseStream(w io.Writer, funcName string, opts Options) error {
+	funcObj, err := m.lookupFunction(funcName)
+	if err != nil {
+		return err
+	}
+
+	opts.IncludeTests = false
+
+	p := newParser(m, opts)
+	p.stream = &streamWriter{w: w, opts: opts}
+	p.process(funcObj, rootDepth(opts))
+
+	return p.stream.finish(p)
+}
+
+// streamWriter writes the processed functions in blocks per package
+type streamWriter struct {
+	w    io.Writer
+	opts Options
+
+	// rootPkg is the package of the root function
+	rootPkg *packages.Package
+
+	// pkg is the package of the open block, nil if no block is open
+	pkg *packages.Package
+
+	// written reports whether anything is written yet
+	written bool
+
+	// err is the first write error, after which nothing is written anymore
+	err error
+}
+
+// write writes the function, opening a new block first if it belongs to another package than the open block
+func (s *streamWriter) write(pkg *packages.Package, fn function) {
+	if s.rootPkg == nil {
+		s.rootPkg = pkg
+	}
+	if s.opts.ExcludeRoot && pkg == s.rootPkg {
+		return
+	}
+
+	if s.pkg == nil || (s.pkg != pkg && !s.opts.Flatten) {
+		if s.pkg != nil {
+			s.closeBlock()
+		}
+		if s.written {
+			s.writeString("\n\n" + formatPkgHeader(s.opts, pkg, s.opts.CodeOnly) + "\n")
+		}
+		if !s.opts.CodeOnly {
+			s.writeString("```" + fenceLanguage(s.opts))
+		}
+		s.pkg = pkg
+	}
+
+	s.writeString("\n" + fn.src)
+}
+
+// closeBlock closes the open block
+func (s *streamWriter) closeBlock() {
+	if !s.opts.CodeOnly {
+		s.writeString("```")
+	}
+	s.pkg = nil
+}
+
+// finish closes the open block, writes the sections following the functions and returns the first write error
+func (s *streamWriter) finish(p *parser) error {
+	if s.pkg != nil {
+		s.closeBlock()
+	}
+
+	for _, section := range p.sections(s.opts.CodeOnly) {
+		if s.written {
+			s.writeString("\n\n")
+		}
+		s.writeString(section)
+	}
+
+	if p.truncated {
+		s.writeString("\n\n" + p.truncationNote(s.opts.CodeOnly))
+	}
+
+	return s.err
+}
+
+// writeString writes the string, unless an earlier write failed
+func (s *streamWriter) writeString(str string) {
+	if s.err != nil {
+		return
+	}
+
+	_, s.err = io.WriteString(s.w, str)
+	s.written = true
+}
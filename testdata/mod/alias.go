@@ -0,0 +1,8 @@
+package mod
+
+import al "example.com/mod/alpha"
+
+// AliasRoot calls a function through an aliased import.
+func AliasRoot() int {
+	return al.Sum(1, 2)
+}
@@ -0,0 +1,11 @@
+package alpha
+
+// Sum adds the numbers.
+func Sum(a, b int) int {
+	return a + b
+}
+
+// GroupAlpha is tagged in the imported package.
+//
+//scparser:group order
+func GroupAlpha() {}
@@ -0,0 +1,13 @@
+package alpha
+
+import "example.com/mod/beta"
+
+type saver struct{}
+
+// Save is called back from beta.
+func (saver) Save() {}
+
+// Start calls into beta, which calls back into alpha.
+func Start() {
+	beta.Mid(saver{})
+}
@@ -0,0 +1,6 @@
+package beta
+
+// Sum subtracts the numbers.
+func Sum(a, b int) int {
+	return a - b
+}
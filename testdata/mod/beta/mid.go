@@ -0,0 +1,11 @@
+package beta
+
+// Saver saves.
+type Saver interface {
+	Save()
+}
+
+// Mid calls the Save method of the saver.
+func Mid(s Saver) {
+	s.Save()
+}
@@ -0,0 +1,31 @@
+package mod
+
+// BudgetRoot calls a deep chain first, and then a function near the root.
+func BudgetRoot() {
+	budgetDeep()
+	budgetNear()
+}
+
+func budgetDeep() { budgetDeeper() }
+
+func budgetDeeper() {
+	_ = []string{
+		`a large body that does not fit in the budget`,
+		`a large body that does not fit in the budget`,
+	}
+}
+
+func budgetNear() { budgetTiny() }
+
+func budgetTiny() {}
+
+// ExportedFirstRoot calls an unexported function before an exported one.
+func ExportedFirstRoot() {
+	firstCalled()
+	SecondCalled()
+}
+
+func firstCalled() {}
+
+// SecondCalled is exported.
+func SecondCalled() {}
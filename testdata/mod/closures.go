@@ -0,0 +1,24 @@
+package mod
+
+// ClosureRoot calls functions inside the function literals of its body.
+func ClosureRoot() {
+	fn := func() {
+		assignedHelper()
+	}
+	fn()
+
+	defer func() {
+		deferredHelper()
+	}()
+
+	func() {
+		nested := func() { nestedHelper() }
+		nested()
+	}()
+}
+
+func assignedHelper() {}
+
+func deferredHelper() {}
+
+func nestedHelper() {}
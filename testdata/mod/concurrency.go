@@ -0,0 +1,30 @@
+package mod
+
+// ChainRoot calls a long chain, and a function further down the chain directly.
+func ChainRoot() {
+	chainB()
+	chainD()
+}
+
+func chainB() { chainC() }
+
+func chainC() { chainD() }
+
+func chainD() { chainE() }
+
+func chainE() { chainF() }
+
+func chainF() { chainG() }
+
+func chainG() {}
+
+// Deep calls an unexported function before an exported one.
+func Deep() {
+	mid()
+	Wide()
+}
+
+func mid() {}
+
+// Wide is exported.
+func Wide() {}
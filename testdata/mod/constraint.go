@@ -0,0 +1,22 @@
+package mod
+
+// Namer has a name.
+type Namer interface {
+	Name() string
+}
+
+// Dog is a dog.
+type Dog struct{}
+
+// Name returns the name of the dog.
+func (Dog) Name() string { return "dog" }
+
+// Describe calls a method of its type parameter.
+func Describe[T Namer](x T) string {
+	return x.Name()
+}
+
+// DescribeRoot describes a dog.
+func DescribeRoot() string {
+	return Describe(Dog{})
+}
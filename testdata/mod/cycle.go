@@ -0,0 +1,27 @@
+package mod
+
+// CycleRoot calls a pair of mutually recursive functions and a chain of functions.
+func CycleRoot() {
+	ping(3)
+	chain1()
+}
+
+func ping(n int) {
+	if n > 0 {
+		pong(n - 1)
+	}
+}
+
+func pong(n int) {
+	if n > 0 {
+		ping(n - 1)
+	}
+}
+
+func chain1() { chain2() }
+
+func chain2() { chain3() }
+
+func chain3() { chain4() }
+
+func chain4() {}
@@ -0,0 +1,19 @@
+package mod
+
+// DedupRoot calls functions with identical bodies.
+func DedupRoot() {
+	_ = sameA(1) + sameB(2)
+	_ = sameWide(3)
+}
+
+func sameA(x int) int { return x + 1 }
+
+func sameB(x int) int { return x + 1 }
+
+func sameWide(x int64) int64 { return x + 1 }
+
+// DupOne and DupTwo are roots with identical bodies.
+func DupOne() { sameA(1) }
+
+// DupTwo has the same body as DupOne.
+func DupTwo() { sameA(1) }
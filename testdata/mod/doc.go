@@ -0,0 +1,9 @@
+package mod
+
+// DocRoot calls a documented helper.
+func DocRoot() {
+	docHelper()
+}
+
+// docHelper does nothing.
+func docHelper() {}
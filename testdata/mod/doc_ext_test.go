@@ -0,0 +1,11 @@
+package mod_test
+
+import (
+	"testing"
+
+	"example.com/mod"
+)
+
+func TestDocRootExternal(t *testing.T) {
+	mod.DocRoot()
+}
@@ -0,0 +1,10 @@
+package mod
+
+import "testing"
+
+func TestDocRoot(t *testing.T) {
+	DocRoot()
+	testHelper(t)
+}
+
+func testHelper(t *testing.T) {}
@@ -0,0 +1,35 @@
+package mod
+
+// Inner declares the promoted method.
+type Inner struct{}
+
+// Hello is promoted through the embedding types.
+func (Inner) Hello() string { return "hello" }
+
+// Middle embeds Inner.
+type Middle struct{ Inner }
+
+// Outer embeds Middle.
+type Outer struct{ *Middle }
+
+// EmbedRoot calls a method promoted through three levels of embedding.
+func EmbedRoot(o Outer) string {
+	return o.Hello()
+}
+
+// Greeter declares a method promoted from an embedded pointer.
+type Greeter struct{}
+
+// Greet is promoted through two levels of embedding.
+func (*Greeter) Greet() string { return "hi" }
+
+// Host embeds Greeter.
+type Host struct{ *Greeter }
+
+// Guest embeds Host.
+type Guest struct{ Host }
+
+// EmbedTwoRoot calls a method promoted through two levels of embedding.
+func EmbedTwoRoot(g Guest) string {
+	return g.Greet()
+}
@@ -0,0 +1,10 @@
+package mod
+
+func applyErr(f func() string) string { return f() }
+
+// ErrValueRoot uses the Error method of the error interface, which has no package, as a value.
+func ErrValueRoot(err error) string {
+	f := err.Error
+	f()
+	return applyErr(err.Error)
+}
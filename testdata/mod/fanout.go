@@ -0,0 +1,18 @@
+package mod
+
+// FanRoot calls four functions, the first one twice.
+func FanRoot() {
+	fanA()
+	fanA()
+	fanB()
+	fanC()
+	fanD()
+}
+
+func fanA() {}
+
+func fanB() {}
+
+func fanC() {}
+
+func fanD() {}
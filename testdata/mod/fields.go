@@ -0,0 +1,23 @@
+package mod
+
+// Handler calls the function in its field.
+type Handler struct{ handle func() }
+
+// NewHandler assigns serve to the field.
+func NewHandler() *Handler {
+	return &Handler{handle: serve}
+}
+
+// Reset assigns reset to the field.
+func (h *Handler) Reset() {
+	h.handle = reset
+}
+
+// FieldRoot calls the function field of the handler.
+func FieldRoot(h *Handler) {
+	h.handle()
+}
+
+func serve() {}
+
+func reset() {}
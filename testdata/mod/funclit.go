@@ -0,0 +1,8 @@
+package mod
+
+// LitRoot is a function literal assigned to a package level variable.
+var LitRoot = func() {
+	litHelper()
+}
+
+func litHelper() {}
@@ -0,0 +1,16 @@
+package mod
+
+// Map applies the function to each element.
+func Map[T, U any](s []T, f func(T) U) []U {
+	r := make([]U, 0, len(s))
+	for _, v := range s {
+		r = append(r, f(v))
+	}
+	return r
+}
+
+// MapRoot calls two instantiations of Map.
+func MapRoot() {
+	Map([]int{1}, func(i int) string { return "" })
+	Map[string, int]([]string{""}, func(s string) int { return 0 })
+}
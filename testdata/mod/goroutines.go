@@ -0,0 +1,21 @@
+package mod
+
+import "sync"
+
+// PoolRoot launches workers directly and through a closure.
+func PoolRoot() {
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			closureWorker()
+		}()
+	}
+	go directWorker()
+	wg.Wait()
+}
+
+func closureWorker() {}
+
+func directWorker() {}
@@ -0,0 +1,37 @@
+package mod
+
+// Store saves values.
+type Store interface {
+	Save(v string)
+}
+
+// Memory keeps values in memory.
+type Memory struct{ values []string }
+
+// Save keeps the value.
+func (m *Memory) Save(v string) { m.values = append(m.values, v) }
+
+// Load returns the values kept in memory.
+func (m *Memory) Load() []string { return m.values }
+
+// Wipe removes the values kept in memory.
+func (m *Memory) Wipe() { m.values = nil }
+
+// Disk only loads values.
+type Disk struct{ path string }
+
+// Load returns the path of the disk.
+func (d *Disk) Load() string { return d.path }
+
+// HintRoot calls Save with hinted implementations.
+func HintRoot(s Store) {
+	s.Save(`x`) //scparser:impl mod.Memory
+	audit()
+	//scparser:impl mod.Disk
+	s.Save(`y`)
+	keep(audit) //scparser:impl mod.Memory
+}
+
+func audit() {}
+
+func keep(f func()) {}
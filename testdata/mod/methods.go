@@ -0,0 +1,23 @@
+package mod
+
+// Counter counts.
+type Counter struct{ n int }
+
+// Get returns the count.
+func (c Counter) Get() int {
+	return c.n
+}
+
+// Inc increments the count.
+func (c *Counter) Inc() {
+	c.n++
+}
+
+func apply(f func()) { f() }
+
+// MethodValueRoot calls a method expression and passes a method value.
+func MethodValueRoot() int {
+	get := Counter.Get
+	apply((&Counter{}).Inc)
+	return get(Counter{})
+}
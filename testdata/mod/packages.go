@@ -0,0 +1,13 @@
+package mod
+
+import "example.com/mod/alpha"
+
+// PackagesRoot calls from alpha to beta and back to alpha.
+func PackagesRoot() {
+	alpha.Start()
+}
+
+// GroupMod is tagged in the importing package.
+//
+//scparser:group order
+func GroupMod() {}
@@ -0,0 +1,5 @@
+package mod
+
+var sharedVar = 1; func SharedLineRoot() { _ = sharedVar; sharedHelper() }
+
+func sharedHelper() {}; var sharedAfter = 2
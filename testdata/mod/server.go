@@ -0,0 +1,14 @@
+package mod
+
+// Server handles requests.
+type Server struct{ name string }
+
+// Handle calls two functions.
+func (s *Server) Handle() {
+	decode()
+	encode()
+}
+
+func decode() {}
+
+func encode() {}
@@ -0,0 +1,17 @@
+package mod
+
+// ShortcutRoot reaches shortTarget through a long path first, and then directly.
+func ShortcutRoot() {
+	longA()
+	shortTarget()
+}
+
+func longA() { longB() }
+
+func longB() { shortTarget() }
+
+func shortTarget() { shortBelow() }
+
+func shortBelow() { shortBottom() }
+
+func shortBottom() {}
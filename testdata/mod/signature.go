@@ -0,0 +1,11 @@
+package mod
+
+import (
+	"example.com/mod/alpha"
+	"example.com/mod/beta"
+)
+
+// SignatureRoot calls two functions of the same signature in different packages.
+func SignatureRoot() int {
+	return alpha.Sum(1, 2) + beta.Sum(1, 2)
+}
@@ -0,0 +1,10 @@
+package mod
+
+import "example.com/mod/alpha"
+
+// StreamRoot calls into alpha and then back into its own package.
+func StreamRoot() int {
+	return alpha.Sum(1, 2) + streamHelper()
+}
+
+func streamHelper() int { return 3 }
@@ -0,0 +1,8 @@
+package deep
+
+import "example.com/mod/alpha"
+
+// DeepRoot is declared in a nested subpackage of the module.
+func DeepRoot() int {
+	return alpha.Sum(1, 2)
+}
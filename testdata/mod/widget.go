@@ -0,0 +1,25 @@
+package mod
+
+// Widget is built by its constructors.
+type Widget struct{ name string }
+
+// NewWidget builds a named widget.
+func NewWidget() (*Widget, error) {
+	return &Widget{name: widgetName()}, nil
+}
+
+// ZeroWidget returns the zero widget.
+func ZeroWidget() Widget {
+	return Widget{}
+}
+
+// Clone copies the widget.
+func (w *Widget) Clone() *Widget {
+	c := *w
+	return &c
+}
+
+func widgetName() string { return `widget` }
+
+// Load returns the name of the widget, declared in another file than the other Load methods.
+func (w *Widget) Load() string { return w.name }
@@ -0,0 +1,24 @@
+package mod
+
+// Deps wires the dependencies.
+type Deps struct {
+	Save func(v string)
+	Load func() string
+}
+
+// Repo stores values.
+type Repo struct{}
+
+// Save stores the value.
+func (r *Repo) Save(v string) {}
+
+func loadDefault() string { return `` }
+
+var repo = &Repo{}
+
+var deps = Deps{Save: repo.Save, Load: loadDefault}
+
+// WiringRoot returns the wired dependencies.
+func WiringRoot() Deps {
+	return deps
+}
@@ -0,0 +1,13 @@
+package mod
+
+// WrapperRoot calls the real implementation through a generated wrapper.
+func WrapperRoot(w *wrapped) int {
+	return w.Do()
+}
+
+type realImpl struct{}
+
+// Do does the real work.
+func (r *realImpl) Do() int { return realHelper() }
+
+func realHelper() int { return 1 }
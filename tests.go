@@ -0,0 +1,184 @@
+package scparser
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// testFunctions returns the source code of the test functions in the _test.go files of the go mod packages
+// which reference one of the processed functions, each followed by the helpers declared in the test files
+// they call. Every test function and helper is included once, in the order they are declared.
+func (p *parser) testFunctions() string {
+	processed := make(map[string]bool)
+	for _, pkg := range p.pkgOrder {
+		for _, fn := range p.functions[pkg] {
+			if fn.decl != nil {
+				processed[fn.name] = true
+			}
+		}
+	}
+
+	var result string
+	included := make(map[string]bool)
+	for _, pkg := range p.loadTestPackages(p.ctx) {
+		// Collect the functions of the test files, the helpers of the tests are among them
+		testFuncs := make(map[string]*ast.FuncDecl)
+		var tests []*ast.FuncDecl
+		for _, file := range pkg.Syntax {
+			if !strings.HasSuffix(pkg.Fset.Position(file.Pos()).Filename, `_test.go`) {
+				continue
+			}
+
+			for _, decl := range file.Decls {
+				fn, ok := decl.(*ast.FuncDecl)
+				if !ok || fn.Name == nil || fn.Body == nil {
+					continue
+				}
+
+				testFuncs[qualifiedName(pkg, fn)] = fn
+				if isTestFunction(fn) && referencesAny(pkg.TypesInfo, fn, processed) {
+					tests = append(tests, fn)
+				}
+			}
+		}
+
+		for _, test := range tests {
+			for _, fn := range testHelpers(pkg.TypesInfo, test, testFuncs) {
+				name := pkg.Fset.Position(fn.Pos()).Filename + `:` + qualifiedName(pkg, fn)
+				if included[name] {
+					continue
+				}
+				included[name] = true
+
+				result += "\n" + p.render(qualifiedName(pkg, fn), func() (string, error) {
+					return p.extractFunction(pkg.Fset, fn)
+				})
+			}
+		}
+	}
+
+	if result == "" {
+		return ""
+	}
+
+	return "\n// tests\n" + result
+}
+
+// isTestFunction reports whether the function is a test, benchmark, fuzz test or example
+func isTestFunction(fn *ast.FuncDecl) bool {
+	if fn.Recv != nil {
+		return false
+	}
+
+	for _, prefix := range []string{`Test`, `Benchmark`, `Fuzz`, `Example`} {
+		if strings.HasPrefix(fn.Name.Name, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// referencesAny reports whether the body of the function uses one of the functions with the given qualified names.
+// The names are compared, as the test variants of the packages have their own types.
+func referencesAny(info *types.Info, fn *ast.FuncDecl, names map[string]bool) bool {
+	var found bool
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok && !found {
+			if obj, ok := info.Uses[ident].(*types.Func); ok && names[obj.Origin().FullName()] {
+				found = true
+			}
+		}
+
+		return !found
+	})
+
+	return found
+}
+
+// testHelpers returns the test function followed by the functions of the test files it calls, transitively
+func testHelpers(info *types.Info, test *ast.FuncDecl, testFuncs map[string]*ast.FuncDecl) []*ast.FuncDecl {
+	result := []*ast.FuncDecl{test}
+	seen := map[*ast.FuncDecl]bool{test: true}
+	for i := 0; i < len(result); i++ {
+		ast.Inspect(result[i].Body, func(n ast.Node) bool {
+			ident, ok := n.(*ast.Ident)
+			if !ok {
+				return true
+			}
+
+			if obj, ok := info.Uses[ident].(*types.Func); ok {
+				if helper, ok := testFuncs[obj.FullName()]; ok && !seen[helper] {
+					seen[helper] = true
+					result = append(result, helper)
+				}
+			}
+
+			return true
+		})
+	}
+
+	return result
+}
+
+// loadTestPackages loads the packages of the main module including their tests, with the context of the parse if
+// not nil. The tests of the other go mod packages are not loaded, like with the Tests option. The packages are loaded
+// once, also when parsing concurrently.
+func (m *ModuleParser) loadTestPackages(ctx context.Context) []*packages.Package {
+	m.testMu.Lock()
+	defer m.testMu.Unlock()
+
+	if m.testPkgs != nil {
+		return m.testPkgs
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var paths []string
+	for _, pkg := range m.pkgs {
+		if pkgPath := strings.TrimSuffix(pkg.PkgPath, `_test`); isGoModPkg(m.goModPaths[:1], pkgPath) {
+			paths = append(paths, pkgPath)
+		}
+	}
+	if len(paths) == 0 {
+		m.testPkgs = []*packages.Package{}
+		return m.testPkgs
+	}
+
+	cfg := *m.config
+	cfg.Context = ctx
+	cfg.Tests = true
+	pkgs, err := packages.Load(&cfg, paths...)
+	if ctx.Err() != nil {
+		// Loading again with another context may succeed
+		return nil
+	}
+	if err != nil {
+		fmt.Println("Warning: loading tests failed:", err)
+		pkgs = []*packages.Package{}
+	}
+
+	m.testPkgs = pkgs
+
+	return m.testPkgs
+}
+
+// testVariantFunc returns the function as declared in the test variant of its package, as loaded with the Tests
+// option. Functions referenced from other packages belong to the packages without tests, which are not loaded.
+func (p *parser) testVariantFunc(fn *types.Func) *types.Func {
+	if _, ok := p.funcToFileAndPkg[fn]; ok {
+		return fn
+	}
+
+	if obj, err := p.declaredFunc(fn); err == nil {
+		return obj
+	}
+
+	return fn
+}
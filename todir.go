@@ -0,0 +1,55 @@
+package scparser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ParseToDir retrieves the source code of the specified function and its underlying functions, like
+// ParseWithOptions, and writes the functions of each package to their own file in outDir. The files are
+// named by package path, e.g. `github.com_x_y.go`, and start with a package clause and the imports used by
+// the functions, as if IncludeImports is set. A *ParseError is returned if the module or function can not be
+// found, or the files can not be written.
+func ParseToDir(outDir, funcPkgPath, funcName string, opts Options) error {
+	m, err := newModuleParser(funcPkgPath, opts)
+	if err != nil {
+		return &ParseError{PkgPath: funcPkgPath, FuncName: funcName, Err: err}
+	}
+
+	if err := m.ParseToDir(outDir, funcName, opts); err != nil {
+		return &ParseError{PkgPath: funcPkgPath, FuncName: funcName, Err: err}
+	}
+
+	return nil
+}
+
+// ParseToDir retrieves the source code of the specified function and its underlying functions and
+// writes the functions of each package to their own file in outDir, see the package level ParseToDir. An error
+// wrapping ErrFunctionNotFound or ErrAmbiguousFunction is returned if the function can not be looked up.
+func (m *ModuleParser) ParseToDir(outDir, funcName string, opts Options) error {
+	// The files start with their imports, so they are usable as Go files
+	opts.IncludeImports = true
+	p, err := m.traverseE(funcName, opts)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return err
+	}
+
+	for k, pkg := range p.pkgOrder {
+		if k == 0 && opts.ExcludeRoot {
+			continue
+		}
+
+		filename := strings.ReplaceAll(pkg.PkgPath, `/`, `_`) + `.go`
+		content := "package " + pkg.Name + "\n" + p.joinPkgFunctions(pkg)
+		if err := os.WriteFile(filepath.Join(outDir, filename), []byte(content), 0o644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
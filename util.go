@@ -1,6 +1,8 @@
 package scparser
 
-import "os"
+import (
+	"go/ast"
+)
 
 func panicOnErr(err error) {
 	if err != nil {
@@ -8,15 +10,26 @@ func panicOnErr(err error) {
 	}
 }
 
-func changeDir(dir string) func() {
-	curDir, err := os.Getwd()
-	panicOnErr(err)
+// uninstantiate returns the expression without parentheses and the type arguments of an instantiation,
+// like `Map[int, string]`
+func uninstantiate(expr ast.Expr) ast.Expr {
+	switch index := unparen(expr).(type) {
+	case *ast.IndexExpr:
+		return unparen(index.X)
+	case *ast.IndexListExpr:
+		return unparen(index.X)
+	}
 
-	err = os.Chdir(dir)
-	panicOnErr(err)
+	return unparen(expr)
+}
 
-	return func() {
-		err := os.Chdir(curDir)
-		panicOnErr(err)
+// unparen returns the expression with any enclosing parentheses removed
+func unparen(expr ast.Expr) ast.Expr {
+	for {
+		paren, ok := expr.(*ast.ParenExpr)
+		if !ok {
+			return expr
+		}
+		expr = paren.X
 	}
 }
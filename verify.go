@@ -0,0 +1,37 @@
+package scparser
+
+import (
+	goparser "go/parser"
+	"go/token"
+	"strings"
+)
+
+// VerifyError lists the functions of which the extracted source code is not valid Go
+type VerifyError struct {
+	// Functions are the qualified names of the functions that failed to parse
+	Functions []string
+}
+
+func (e *VerifyError) Error() string {
+	return "extracted source code does not parse: " + strings.Join(e.Functions, ", ")
+}
+
+// verify parses the extracted source code of each function on its own, wrapped in a minimal package,
+// and returns a VerifyError listing the functions that fail to parse
+func (p *parser) verify() error {
+	var failed []string
+	for _, pkg := range p.pkgOrder {
+		for _, fn := range p.functions[pkg] {
+			_, err := goparser.ParseFile(token.NewFileSet(), fn.start.Filename, "package "+pkg.Name+"\n"+fn.src, goparser.ParseComments)
+			if err != nil {
+				failed = append(failed, fn.name)
+			}
+		}
+	}
+
+	if len(failed) == 0 {
+		return nil
+	}
+
+	return &VerifyError{Functions: failed}
+}
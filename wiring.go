@@ -0,0 +1,144 @@
+package scparser
+
+import (
+	"go/ast"
+	"go/types"
+)
+
+// collectVarFuncs collects the functions and methods referenced as values in the composite literals
+// initializing the variables of the declaration, e.g. `var deps = Deps{Save: repo.Save}`
+func (m *ModuleParser) collectVarFuncs(info *types.Info, gd *ast.GenDecl) {
+	for _, spec := range gd.Specs {
+		vs, ok := spec.(*ast.ValueSpec)
+		if !ok {
+			continue
+		}
+
+		for i, name := range vs.Names {
+			v, ok := info.Defs[name].(*types.Var)
+			if !ok || i >= len(vs.Values) {
+				continue
+			}
+
+			ast.Inspect(vs.Values[i], func(n ast.Node) bool {
+				lit, ok := n.(*ast.CompositeLit)
+				if !ok {
+					return true
+				}
+
+				for _, elt := range lit.Elts {
+					if kv, ok := elt.(*ast.KeyValueExpr); ok {
+						elt = kv.Value
+					}
+					if fn := funcValue(info, elt); fn != nil {
+						m.varFuncs[v] = append(m.varFuncs[v], fn)
+					}
+				}
+
+				return true
+			})
+		}
+	}
+}
+
+// collectFieldFuncs collects the functions and methods assigned to the struct fields of function type in the
+// file, in keyed composite literals like `&Server{handler: s.serve}` or in assignments like `s.handler = serve`
+func (m *ModuleParser) collectFieldFuncs(info *types.Info, file *ast.File) {
+	assign := func(field *ast.Ident, value ast.Expr) {
+		v, ok := info.ObjectOf(field).(*types.Var)
+		if !ok || !v.IsField() {
+			return
+		}
+		if _, ok := v.Type().Underlying().(*types.Signature); !ok {
+			return
+		}
+
+		fn := funcValue(info, value)
+		if fn == nil {
+			return
+		}
+		v = v.Origin()
+		for _, assigned := range m.fieldFuncs[v] {
+			if assigned == fn {
+				return
+			}
+		}
+		m.fieldFuncs[v] = append(m.fieldFuncs[v], fn)
+	}
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.CompositeLit:
+			for _, elt := range n.Elts {
+				if kv, ok := elt.(*ast.KeyValueExpr); ok {
+					if key, ok := kv.Key.(*ast.Ident); ok {
+						assign(key, kv.Value)
+					}
+				}
+			}
+		case *ast.AssignStmt:
+			if len(n.Lhs) != len(n.Rhs) {
+				return true
+			}
+			for i, lhs := range n.Lhs {
+				if sel, ok := unparen(lhs).(*ast.SelectorExpr); ok {
+					assign(sel.Sel, n.Rhs[i])
+				}
+			}
+		}
+
+		return true
+	})
+}
+
+// processVarFuncs processes the functions referenced in the composite literal initializer of the
+// package level variable the identifier refers to
+func (p *parser) processVarFuncs(info *types.Info, ident *ast.Ident, depth int) {
+	v, ok := info.Uses[ident].(*types.Var)
+	if !ok {
+		return
+	}
+
+	for _, fn := range p.varFuncs[v] {
+		p.followFunction(fn, depth)
+	}
+}
+
+// localFuncs collects the functions and methods assigned to the local variables of the function body,
+// e.g. `fn := doWork` or `var fn = s.Handle`, so the functions are followed where the variable is called
+func localFuncs(info *types.Info, body *ast.BlockStmt) map[*types.Var][]*types.Func {
+	locals := make(map[*types.Var][]*types.Func)
+	assign := func(name *ast.Ident, value ast.Expr) {
+		v, ok := info.ObjectOf(name).(*types.Var)
+		if !ok || v.Pkg() == nil || v.Parent() == v.Pkg().Scope() {
+			return
+		}
+		if fn := funcValue(info, value); fn != nil {
+			locals[v] = append(locals[v], fn)
+		}
+	}
+
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			if len(n.Lhs) != len(n.Rhs) {
+				return true
+			}
+			for i, lhs := range n.Lhs {
+				if name, ok := unparen(lhs).(*ast.Ident); ok {
+					assign(name, n.Rhs[i])
+				}
+			}
+		case *ast.ValueSpec:
+			for i, name := range n.Names {
+				if i < len(n.Values) {
+					assign(name, n.Values[i])
+				}
+			}
+		}
+
+		return true
+	})
+
+	return locals
+}
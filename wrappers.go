@@ -0,0 +1,93 @@
+package scparser
+
+import (
+	"go/ast"
+	"go/types"
+	"regexp"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// generatedPattern matches the comment marking a file as generated, see https://go.dev/s/generatedcode
+var generatedPattern = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// isGenerated reports whether the file is marked as generated by a comment before the package clause
+func isGenerated(file *ast.File) bool {
+	for _, cg := range file.Comments {
+		if cg.Pos() > file.Package {
+			return false
+		}
+
+		for _, c := range cg.List {
+			if generatedPattern.MatchString(c.Text) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// delegateOf returns the go mod function the function delegates to, if its body consists of a single call.
+// Both `w.inner.Do()` and `return w.inner.Do()` are considered delegating calls.
+func (p *parser) delegateOf(pkg *packages.Package, fn *ast.FuncDecl) *types.Func {
+	if fn.Body == nil || len(fn.Body.List) != 1 {
+		return nil
+	}
+
+	var call ast.Expr
+	switch stmt := fn.Body.List[0].(type) {
+	case *ast.ExprStmt:
+		call = stmt.X
+	case *ast.ReturnStmt:
+		if len(stmt.Results) != 1 {
+			return nil
+		}
+		call = stmt.Results[0]
+	default:
+		return nil
+	}
+
+	ce, ok := unparen(call).(*ast.CallExpr)
+	if !ok {
+		return nil
+	}
+
+	var ident *ast.Ident
+	switch fun := unparen(ce.Fun).(type) {
+	case *ast.Ident:
+		ident = fun
+	case *ast.SelectorExpr:
+		ident = fun.Sel
+	default:
+		return nil
+	}
+
+	delegate, ok := pkg.TypesInfo.ObjectOf(ident).(*types.Func)
+	if !ok {
+		return nil
+	}
+	if _, ok := p.funcToFileAndPkg[delegate]; !ok {
+		return nil
+	}
+
+	return delegate
+}
+
+// processWrapper marks the elision of a generated wrapper in the output and processes the function it
+// delegates to in its place, at the same depth
+func (p *parser) processWrapper(pkg *packages.Package, fn *ast.FuncDecl, delegate *types.Func, depth int) {
+	name := qualifiedName(pkg, fn)
+	note := "// " + name + ": generated wrapper of " + delegate.FullName() + " elided\n"
+	pos := pkg.Fset.Position(fn.Pos())
+	if !p.addFunction(pkg, function{name: name, start: pos, end: pkg.Fset.Position(fn.End()), src: note, level: p.levelOf(delegate)}) {
+		return
+	}
+
+	prevCaller := p.caller
+	p.caller = name
+	p.recordCall(delegate)
+	p.caller = prevCaller
+
+	p.processFunction(delegate, depth)
+}
@@ -0,0 +1,54 @@
+package scparser
+
+import "io"
+
+// ParseTo is like Parse, but writes the output to w one package block at a time instead of returning it,
+// e.g. to pipe it to a file or HTTP response. The output is the same as the output of Parse.
+func ParseTo(w io.Writer, funcPkgPath, funcName string, excludeRoot, codeOnly bool) error {
+	m, err := newModuleParser(funcPkgPath, Options{})
+	if err != nil {
+		return &ParseError{PkgPath: funcPkgPath, FuncName: funcName, Err: err}
+	}
+
+	if err := m.ParseTo(w, funcName, Options{ExcludeRoot: excludeRoot, CodeOnly: codeOnly}); err != nil {
+		return &ParseError{PkgPath: funcPkgPath, FuncName: funcName, Err: err}
+	}
+
+	return nil
+}
+
+// ParseTo is like ParseWithOptions, but writes the output to w one package block at a time instead of
+// returning it. Unlike ParseStream, the functions are grouped by package the same way as in the output
+// of ParseWithOptions, so they are only written once all functions are processed.
+func (m *ModuleParser) ParseTo(w io.Writer, funcName string, opts Options) error {
+	funcObj, err := m.lookupFunction(funcName)
+	if err != nil {
+		return err
+	}
+
+	p := newParser(m, opts)
+	p.process(funcObj, rootDepth(opts))
+
+	return p.writeTo(w, opts.ExcludeRoot, opts.CodeOnly)
+}
+
+// blockWriter writes the blocks of the output, keeping the first write error
+type blockWriter struct {
+	w io.Writer
+
+	// written reports whether anything is written yet
+	written bool
+
+	// err is the first write error, after which nothing is written anymore
+	err error
+}
+
+// writeString writes the string, unless an earlier write failed. Empty strings are not written.
+func (b *blockWriter) writeString(str string) {
+	if b.err != nil || str == "" {
+		return
+	}
+
+	_, b.err = io.WriteString(b.w, str)
+	b.written = true
+}